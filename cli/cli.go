@@ -0,0 +1,240 @@
+// Package cli implements the sparkle command line: `run` executes features
+// and `list` prints them without running. A test binary imports the
+// packages registering its features and hands over to Main:
+//
+//	import _ "example.com/project/features/login"
+//
+//	func main() {
+//		os.Exit(cli.Main(os.Args[1:]))
+//	}
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jimmyseraph/sparkle/engine"
+	"github.com/jimmyseraph/sparkle/handler"
+)
+
+// Exit codes of Main.
+const (
+	EXIT_OK = iota
+	// EXIT_FAILED means at least one case failed.
+	EXIT_FAILED
+	// EXIT_USAGE means the command line was invalid.
+	EXIT_USAGE
+	// EXIT_ERROR means the run could not be set up or a reporter failed.
+	EXIT_ERROR
+)
+
+const usage = `Usage: sparkle <command> [flags]
+
+Commands:
+  run     run the features and report the results
+  list    print the features and cases that run would execute
+
+Run "sparkle <command> -h" for the flags of a command.
+`
+
+// Main runs the command in args, without the program name, against
+// features, or every registered feature when none is given, and returns the
+// exit code.
+func Main(args []string, features ...*engine.TestFeature) int {
+	if len(features) == 0 {
+		features = engine.Registered()
+	}
+	return run(args, features, os.Stdout, os.Stderr)
+}
+
+func run(args []string, features []*engine.TestFeature, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return EXIT_USAGE
+	}
+	switch args[0] {
+	case "run":
+		return runCommand(args[1:], features, stdout, stderr)
+	case "list":
+		return listCommand(args[1:], features, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return EXIT_OK
+	}
+	fmt.Fprintf(stderr, "sparkle: unknown command %q\n\n%s", args[0], usage)
+	return EXIT_USAGE
+}
+
+// options are the flags shared by run and list.
+type options struct {
+	tags        string
+	excludeTags string
+	name        string
+}
+
+func (o *options) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.tags, "tags", "", "run only cases matching this tag expression, as \"(api || grpc) && !slow\" or \"smoke,api\"")
+	flags.StringVar(&o.excludeTags, "exclude-tags", "", "skip cases matching this tag expression")
+	flags.StringVar(&o.name, "name", "", "run only cases whose \"feature/case\" name matches this regular expression")
+}
+
+// filter combines the flags into a single engine.Filter.
+func (o *options) filter() (*engine.Filter, error) {
+	tags := strings.TrimSpace(o.tags)
+	if exclude := strings.TrimSpace(o.excludeTags); exclude != "" {
+		if tags == "" {
+			tags = "!(" + exclude + ")"
+		} else {
+			tags = "(" + tags + ") && !(" + exclude + ")"
+		}
+	}
+	return engine.NewFilter(tags, o.name)
+}
+
+func runCommand(args []string, features []*engine.TestFeature, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sparkle run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var opts options
+	opts.register(flags)
+	suiteName := flags.String("suite", "sparkle", "name of the test suite")
+	parallel := flags.Int("parallel", 0, "maximum number of features, and cases per feature, run at the same time")
+	timeout := flags.Duration("timeout", 0, "default timeout of every case and hook, 0 for none")
+	report := flags.String("report", "", "comma separated reporters: "+strings.Join(handler.Reporters(), ", ")+
+		"; defaults to $"+handler.REPORTER_ENV+" or console")
+	output := flags.String("output", "", "directory the file reporters write to")
+	if code, ok := parse(flags, args); !ok {
+		return code
+	}
+
+	f, err := opts.filter()
+	if err != nil {
+		fmt.Fprintf(stderr, "sparkle: %s\n", err.Error())
+		return EXIT_USAGE
+	}
+	handlers, err := reporters(*report, *output)
+	if err != nil {
+		fmt.Fprintf(stderr, "sparkle: %s\n", err.Error())
+		return EXIT_USAGE
+	}
+	suite := &engine.TestSuite{
+		Name:     *suiteName,
+		Features: features,
+		Filter:   f,
+		Handlers: handlers,
+		Parallel: *parallel,
+		Timeout:  *timeout,
+	}
+	summary := suite.Run()
+	if len(summary.Errors) > 0 {
+		for _, err := range summary.Errors {
+			fmt.Fprintf(stderr, "sparkle: %s\n", err.Error())
+		}
+		return EXIT_ERROR
+	}
+	if !summary.Success() {
+		return EXIT_FAILED
+	}
+	return EXIT_OK
+}
+
+func listCommand(args []string, features []*engine.TestFeature, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sparkle list", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var opts options
+	opts.register(flags)
+	if code, ok := parse(flags, args); !ok {
+		return code
+	}
+	f, err := opts.filter()
+	if err != nil {
+		fmt.Fprintf(stderr, "sparkle: %s\n", err.Error())
+		return EXIT_USAGE
+	}
+	for _, feature := range features {
+		testCases := feature.Selected(f)
+		if len(testCases) == 0 {
+			continue
+		}
+		line := feature.Name
+		if len(feature.Tags) > 0 {
+			line += " [" + strings.Join(feature.Tags, ", ") + "]"
+		}
+		fmt.Fprintln(stdout, line)
+		for _, testCase := range testCases {
+			line := "  " + testCase.Name
+			if len(testCase.Tag) > 0 {
+				line += " [" + strings.Join(testCase.Tag, ", ") + "]"
+			}
+			if len(testCase.DependsOn) > 0 {
+				line += " (after " + strings.Join(testCase.DependsOn, ", ") + ")"
+			}
+			if testCase.Ignore || testCase.Case == nil {
+				line += " (ignored)"
+			}
+			fmt.Fprintln(stdout, line)
+		}
+	}
+	return EXIT_OK
+}
+
+// parse parses the flags of a command. It returns false with the exit code
+// when the command should stop.
+func parse(flags *flag.FlagSet, args []string) (int, bool) {
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return EXIT_OK, false
+		}
+		return EXIT_USAGE, false
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(flags.Output(), "unexpected arguments: %s\n", strings.Join(flags.Args(), " "))
+		return EXIT_USAGE, false
+	}
+	return EXIT_OK, true
+}
+
+// defaultOutputs are the names the file reporters use inside -output.
+var defaultOutputs = map[string]string{
+	"junit":  "junit.xml",
+	"html":   "report.html",
+	"allure": "allure-results",
+	"ndjson": "events.ndjson",
+}
+
+// reporters creates the reporters of a -report list, or those of
+// SPARKLE_REPORTER when it is empty. File reporters without an explicit
+// output write into dir.
+func reporters(spec string, dir string) ([]engine.MessageHandler, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = os.Getenv(handler.REPORTER_ENV)
+	}
+	if strings.TrimSpace(spec) == "" {
+		spec = "console"
+	}
+	if dir == "" {
+		return handler.Parse(spec)
+	}
+	items := make([]string, 0)
+	for _, item := range splitList(spec) {
+		if name := strings.ToLower(item); !strings.Contains(item, "=") && defaultOutputs[name] != "" {
+			item += "=" + filepath.Join(dir, defaultOutputs[name])
+		}
+		items = append(items, item)
+	}
+	return handler.Parse(strings.Join(items, ","))
+}
+
+func splitList(s string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,133 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jimmyseraph/sparkle/engine"
+	"github.com/jimmyseraph/sparkle/handler"
+)
+
+func testFeatures(ran *[]string) []*engine.TestFeature {
+	record := func(name string, fail bool) func(assertion *engine.Assertion, args ...interface{}) {
+		return func(assertion *engine.Assertion, args ...interface{}) {
+			*ran = append(*ran, name)
+			if fail {
+				assertion.AssertFail("expected")
+			}
+		}
+	}
+	return []*engine.TestFeature{
+		{
+			Name: "login",
+			TestCases: []engine.TestCase{
+				{Name: "valid", Tag: []string{"smoke"}, Case: record("login/valid", false)},
+				{Name: "locked", Tag: []string{"slow"}, Case: record("login/locked", true)},
+			},
+		},
+		{
+			Name: "orders",
+			Tags: []string{"api"},
+			TestCases: []engine.TestCase{
+				{Name: "create", Tag: []string{"smoke", "slow"}, Case: record("orders/create", false)},
+				{Name: "todo", Ignore: true},
+			},
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+		ran  string
+	}{
+		{"all", []string{"run"}, EXIT_FAILED, "login/valid login/locked orders/create"},
+		{"tags", []string{"run", "-tags", "smoke"}, EXIT_OK, "login/valid orders/create"},
+		{"exclude tags", []string{"run", "-tags", "smoke", "-exclude-tags", "slow"}, EXIT_OK, "login/valid"},
+		{"expression", []string{"run", "-tags", "(smoke || slow) && !api"}, EXIT_FAILED, "login/valid login/locked"},
+		{"feature tags", []string{"run", "-tags", "api"}, EXIT_OK, "orders/create"},
+		{"bad expression", []string{"run", "-tags", "smoke &&"}, EXIT_USAGE, ""},
+		{"name", []string{"run", "-name", "^login/"}, EXIT_FAILED, "login/valid login/locked"},
+		{"parallel", []string{"run", "-parallel", "2", "-name", "create"}, EXIT_OK, "orders/create"},
+		{"bad name", []string{"run", "-name", "("}, EXIT_USAGE, ""},
+		{"bad reporter", []string{"run", "-report", "nope"}, EXIT_USAGE, ""},
+		{"bad flag", []string{"run", "-nope"}, EXIT_USAGE, ""},
+		{"unknown command", []string{"walk"}, EXIT_USAGE, ""},
+		{"no command", []string{}, EXIT_USAGE, ""},
+	}
+	t.Setenv(handler.REPORTER_ENV, "junit="+filepath.Join(t.TempDir(), "junit.xml"))
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ran := make([]string, 0)
+			var stdout, stderr bytes.Buffer
+			code := run(test.args, testFeatures(&ran), &stdout, &stderr)
+			if code != test.code {
+				t.Fatalf("exit code %d, want %d: %s", code, test.code, stderr.String())
+			}
+			if got := strings.Join(ran, " "); got != test.ran {
+				t.Fatalf("ran %q, want %q", got, test.ran)
+			}
+		})
+	}
+}
+
+func TestRunOutput(t *testing.T) {
+	dir := t.TempDir()
+	ran := make([]string, 0)
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"run", "-tags", "smoke", "-report", "junit,html", "-output", dir}, testFeatures(&ran), &stdout, &stderr)
+	if code != EXIT_OK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	for _, name := range []string{"junit.xml", "report.html"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("missing report: %v", err)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	ran := make([]string, 0)
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"list"}, testFeatures(&ran), &stdout, &stderr); code != EXIT_OK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	expected := "login\n  valid [smoke]\n  locked [slow]\norders [api]\n  create [smoke, slow]\n  todo (ignored)\n"
+	if stdout.String() != expected {
+		t.Fatalf("unexpected list:\n%s", stdout.String())
+	}
+	if len(ran) != 0 {
+		t.Fatalf("list ran %v", ran)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"list", "-exclude-tags", "slow"}, testFeatures(&ran), &stdout, &stderr); code != EXIT_OK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if expected := "login\n  valid [smoke]\norders [api]\n  todo (ignored)\n"; stdout.String() != expected {
+		t.Fatalf("unexpected filtered list:\n%s", stdout.String())
+	}
+
+	flow := []*engine.TestFeature{
+		{
+			Name: "flow",
+			TestCases: []engine.TestCase{
+				{Name: "create", Case: func(assertion *engine.Assertion, args ...interface{}) {}},
+				{Name: "login", Tag: []string{"auth"}, DependsOn: []string{"create"}, Case: func(assertion *engine.Assertion, args ...interface{}) {}},
+				{Name: "delete", DependsOn: []string{"login"}, Case: func(assertion *engine.Assertion, args ...interface{}) {}},
+			},
+		},
+	}
+	stdout.Reset()
+	if code := run([]string{"list", "-tags", "auth"}, flow, &stdout, &stderr); code != EXIT_OK {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+	if expected := "flow\n  create\n  login [auth] (after create)\n"; stdout.String() != expected {
+		t.Fatalf("list without prerequisites:\n%s", stdout.String())
+	}
+}
@@ -1,4 +1,14 @@
+// Command sparkle is the sparkle command line without any features of its
+// own. Projects build their own binary that imports their feature packages,
+// as the cli package describes.
 package main
 
+import (
+	"os"
+
+	"github.com/jimmyseraph/sparkle/cli"
+)
+
 func main() {
+	os.Exit(cli.Main(os.Args[1:]))
 }
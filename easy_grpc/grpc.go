@@ -7,6 +7,8 @@ import (
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 )
 
 type grpcHandler struct {
@@ -17,13 +19,20 @@ type grpcHandler struct {
 }
 
 func NewGRPCHandler(address string) *grpcHandler {
+	return NewGRPCHandlerWithContext(context.Background(), address)
+}
+
+// NewGRPCHandlerWithContext derives the handler context from ctx, so calls
+// made with handler.Ctx are cancelled together with ctx. Inside a test case
+// pass assertion.Context().
+func NewGRPCHandlerWithContext(ctx context.Context, address string) *grpcHandler {
 	log, _ := zap.NewDevelopment()
 	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
 		log.Error("cannot established connetion to grpc server.", zap.String("address", address), zap.String("error", err.Error()))
 		panic(err)
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	ctx, cancel := context.WithTimeout(ctx, time.Second)
 	handler := &grpcHandler{
 		Conn:   conn,
 		Ctx:    ctx,
@@ -37,3 +46,10 @@ func (h *grpcHandler) Close() {
 	defer h.Cancel()
 	defer h.Conn.Close()
 }
+
+// Dump renders a request or response message as indented JSON, for an
+// engine.GRPC_REQUEST or engine.GRPC_RESPONSE Detail recorded with
+// Assertion.Capture.
+func Dump(message proto.Message) string {
+	return protojson.MarshalOptions{Multiline: true}.Format(message)
+}
@@ -1,6 +1,7 @@
 package easy_http
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -8,9 +9,12 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/jimmyseraph/sparkle/utils/jsonpath"
+
 	"go.uber.org/zap"
 	"golang.org/x/net/http2"
 )
@@ -133,6 +137,12 @@ func (h *requestHandler) EnableHttp2(enable bool) {
 }
 
 func (h *requestHandler) Execute() (r *response, err error) {
+	return h.ExecuteContext(context.Background())
+}
+
+// ExecuteContext sends the request like Execute, aborting it once ctx is
+// done. Inside a test case pass assertion.Context().
+func (h *requestHandler) ExecuteContext(ctx context.Context) (r *response, err error) {
 
 	if strings.TrimSpace(h.Url) == "" {
 		h.log.Error("no url specified", zap.String("url", h.Url))
@@ -142,7 +152,7 @@ func (h *requestHandler) Execute() (r *response, err error) {
 		h.log.Error("no method specified", zap.String("method", h.Method))
 		return nil, errors.New("no method specified")
 	}
-	req, err := http.NewRequest(h.Method, h.Url, strings.NewReader(h.Body))
+	req, err := http.NewRequestWithContext(ctx, h.Method, h.Url, strings.NewReader(h.Body))
 	if err != nil {
 		h.log.Error("cannot build request", zap.String("method", h.Method), zap.String("url", h.Url), zap.String("body", h.Body))
 		return nil, err
@@ -179,6 +189,21 @@ func (h *requestHandler) Execute() (r *response, err error) {
 	return r, nil
 }
 
+// Dump renders the request as text, for an engine.HTTP_REQUEST Detail
+// recorded with Assertion.Capture.
+func (h *requestHandler) Dump() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s\n", h.Method, h.Url)
+	dumpHeaders(&b, h.Headers)
+	for _, name := range sortedKeys(h.Cookies) {
+		fmt.Fprintf(&b, "Cookie: %s=%v\n", name, h.Cookies[name])
+	}
+	if h.Body != "" {
+		fmt.Fprintf(&b, "\n%s", h.Body)
+	}
+	return b.String()
+}
+
 type response struct {
 	Body       string
 	Headers    map[string][]string
@@ -216,8 +241,50 @@ func NewResponse(resp *http.Response, duration time.Duration, log *zap.Logger) *
 	return r
 }
 
+// GetBodyByType unmarshals the JSON body into t, which must be a pointer.
 func (resp *response) GetBodyByType(t interface{}) error {
-	return json.Unmarshal([]byte(resp.Body), &t)
+	return json.Unmarshal([]byte(resp.Body), t)
+}
+
+// JSON returns the value a JSONPath expression such as
+// "$.data.items[0].id" selects in the JSON body. Objects, arrays and numbers
+// come back as map[string]interface{}, []interface{} and json.Number, so
+// large integer IDs keep every digit.
+func (resp *response) JSON(path string) (interface{}, error) {
+	return jsonpath.GetJSON([]byte(resp.Body), path)
+}
+
+// Dump renders the response as text, for an engine.HTTP_RESPONSE Detail
+// recorded with Assertion.Capture.
+func (resp *response) Dump() string {
+	var b strings.Builder
+	if resp.Status == "" {
+		fmt.Fprintf(&b, "no response after %v\n", resp.Duration)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%s %s (%v)\n", resp.Proto, resp.Status, resp.Duration)
+	dumpHeaders(&b, resp.Headers)
+	if resp.Body != "" {
+		fmt.Fprintf(&b, "\n%s", resp.Body)
+	}
+	return b.String()
+}
+
+func dumpHeaders(b *strings.Builder, headers map[string][]string) {
+	for _, name := range sortedKeys(headers) {
+		for _, value := range headers[name] {
+			fmt.Fprintf(b, "%s: %s\n", name, value)
+		}
+	}
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 type Method int
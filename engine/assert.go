@@ -0,0 +1,296 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The Assert methods below record a readable Detail and fail the Assertion
+// when the check does not hold. They report whether it held, so that a case
+// can stop on its own after a failed precondition.
+
+func (a *Assertion) AssertTrue(value bool, title string) bool {
+	if !value {
+		return a.failf("%s expected true, but actual was false", title)
+	}
+	return true
+}
+
+func (a *Assertion) AssertFalse(value bool, title string) bool {
+	if value {
+		return a.failf("%s expected false, but actual was true", title)
+	}
+	return true
+}
+
+func (a *Assertion) AssertNil(value interface{}, title string) bool {
+	if !isNil(value) {
+		return a.failf("%s expected nil, but actual was %T(%v)", title, value, value)
+	}
+	return true
+}
+
+func (a *Assertion) AssertNotNil(value interface{}, title string) bool {
+	if isNil(value) {
+		return a.failf("%s expected not nil, but actual was %T(%v)", title, value, value)
+	}
+	return true
+}
+
+// AssertContains checks that a string contains a substring, a slice or an
+// array contains an element, or a map contains a key.
+func (a *Assertion) AssertContains(container interface{}, element interface{}, title string) bool {
+	found, err := contains(container, element)
+	if err != nil {
+		return a.failf("%s %s", title, err.Error())
+	}
+	if !found {
+		return a.failf("%s expected %T(%v) to contain %T(%v)", title, container, container, element, element)
+	}
+	return true
+}
+
+// AssertGreater checks that actual > than. Both must be numbers, strings,
+// or times.
+func (a *Assertion) AssertGreater(actual interface{}, than interface{}, title string) bool {
+	order, err := compare(actual, than)
+	if err != nil {
+		return a.failf("%s %s", title, err.Error())
+	}
+	if order <= 0 {
+		return a.failf("%s expected %T(%v) to be greater than %T(%v)", title, actual, actual, than, than)
+	}
+	return true
+}
+
+// AssertLess checks that actual < than. Both must be numbers, strings, or
+// times.
+func (a *Assertion) AssertLess(actual interface{}, than interface{}, title string) bool {
+	order, err := compare(actual, than)
+	if err != nil {
+		return a.failf("%s %s", title, err.Error())
+	}
+	if order >= 0 {
+		return a.failf("%s expected %T(%v) to be less than %T(%v)", title, actual, actual, than, than)
+	}
+	return true
+}
+
+// AssertInDelta checks that actual is within delta of expected.
+func (a *Assertion) AssertInDelta(expected float64, actual float64, delta float64, title string) bool {
+	if diff := expected - actual; diff > delta || diff < -delta || diff != diff {
+		return a.failf("%s expected %v within %v, but actual was %v", title, expected, delta, actual)
+	}
+	return true
+}
+
+func (a *Assertion) AssertMatchesRegex(pattern string, actual string, title string) bool {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return a.failf("%s invalid pattern %q: %s", title, pattern, err.Error())
+	}
+	if !re.MatchString(actual) {
+		return a.failf("%s expected %q to match %q", title, actual, pattern)
+	}
+	return true
+}
+
+// AssertErrorIs checks that target is in the chain of err, as errors.Is.
+func (a *Assertion) AssertErrorIs(err error, target error, title string) bool {
+	if !errors.Is(err, target) {
+		return a.failf("%s expected error %v in chain, but actual was %v", title, target, err)
+	}
+	return true
+}
+
+// AssertErrorAs checks that an error in the chain of err can be assigned to
+// target, as errors.As, and sets target to it.
+func (a *Assertion) AssertErrorAs(err error, target interface{}, title string) bool {
+	targetRV := reflect.ValueOf(target)
+	if target == nil || targetRV.Kind() != reflect.Ptr || targetRV.IsNil() {
+		return a.failf("%s target must be a non-nil pointer, but was %T", title, target)
+	}
+	targetType := targetRV.Type().Elem()
+	if targetType.Kind() != reflect.Interface && !targetType.Implements(reflect.TypeOf((*error)(nil)).Elem()) {
+		return a.failf("%s target must point to an interface or an error, but was %T", title, target)
+	}
+	if !errors.As(err, target) {
+		return a.failf("%s expected error of type %v in chain, but actual was %T(%v)", title, targetType, err, err)
+	}
+	return true
+}
+
+// AssertLen checks the length of a string, slice, array, map or channel.
+func (a *Assertion) AssertLen(object interface{}, length int, title string) bool {
+	actual, ok := lengthOf(object)
+	if !ok {
+		return a.failf("%s cannot get length of %T(%v)", title, object, object)
+	}
+	if actual != length {
+		return a.failf("%s expected length %d, but actual was %d: %v", title, length, actual, object)
+	}
+	return true
+}
+
+// AssertEmpty checks that object is nil, has length zero, or is the zero
+// value of its type.
+func (a *Assertion) AssertEmpty(object interface{}, title string) bool {
+	if !isEmpty(object) {
+		return a.failf("%s expected empty, but actual was %T(%v)", title, object, object)
+	}
+	return true
+}
+
+func (a *Assertion) AssertPanics(fn func(), title string) bool {
+	if x, _ := capture(fn); x == nil {
+		return a.failf("%s expected a panic, but function returned normally", title)
+	}
+	return true
+}
+
+// failf records an assert Detail, fails the Assertion and returns false.
+func (a *Assertion) failf(format string, args ...interface{}) bool {
+	a.appendDetail(Detail{
+		Name:       ASSERT,
+		Message:    fmt.Sprintf(format, args...),
+		RecordTime: time.Now(),
+	})
+	a.fail()
+	return false
+}
+
+func isNil(value interface{}) bool {
+	return value == nil || isNilValue(reflect.ValueOf(value))
+}
+
+func isEmpty(value interface{}) bool {
+	if isNil(value) {
+		return true
+	}
+	if length, ok := lengthOf(value); ok {
+		return length == 0
+	}
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr {
+		return isEmpty(rv.Elem().Interface())
+	}
+	return rv.IsZero()
+}
+
+func lengthOf(value interface{}) (int, bool) {
+	if value == nil {
+		return 0, false
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map, reflect.Chan:
+		return rv.Len(), true
+	}
+	return 0, false
+}
+
+func contains(container interface{}, element interface{}) (bool, error) {
+	if container == nil {
+		return false, fmt.Errorf("cannot look for %T(%v) in nil", element, element)
+	}
+	rv := reflect.ValueOf(container)
+	switch rv.Kind() {
+	case reflect.String:
+		sub, ok := element.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot look for %T(%v) in a string", element, element)
+		}
+		return strings.Contains(rv.String(), sub), nil
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if reflect.DeepEqual(rv.Index(i).Interface(), element) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		for _, key := range rv.MapKeys() {
+			if reflect.DeepEqual(key.Interface(), element) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("cannot look for %T(%v) in %T", element, element, container)
+}
+
+// compare orders two numbers of any numeric kind, two strings or two times.
+func compare(x interface{}, y interface{}) (int, error) {
+	if xt, ok := x.(time.Time); ok {
+		if yt, ok := y.(time.Time); ok {
+			switch {
+			case xt.Before(yt):
+				return -1, nil
+			case xt.After(yt):
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	xv, yv := reflect.ValueOf(x), reflect.ValueOf(y)
+	switch {
+	case isKind(xv, reflect.String) && isKind(yv, reflect.String):
+		return strings.Compare(xv.String(), yv.String()), nil
+	case isInt(xv) && isInt(yv):
+		return order(xv.Int() < yv.Int(), xv.Int() > yv.Int()), nil
+	case isUint(xv) && isUint(yv):
+		return order(xv.Uint() < yv.Uint(), xv.Uint() > yv.Uint()), nil
+	case isNumber(xv) && isNumber(yv):
+		xf, yf := toFloat(xv), toFloat(yv)
+		return order(xf < yf, xf > yf), nil
+	}
+	return 0, fmt.Errorf("cannot compare %T(%v) with %T(%v)", x, x, y, y)
+}
+
+func order(less bool, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+	return 0
+}
+
+func isKind(rv reflect.Value, kinds ...reflect.Kind) bool {
+	if !rv.IsValid() {
+		return false
+	}
+	for _, kind := range kinds {
+		if rv.Kind() == kind {
+			return true
+		}
+	}
+	return false
+}
+
+func isInt(rv reflect.Value) bool {
+	return isKind(rv, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64)
+}
+
+func isUint(rv reflect.Value) bool {
+	return isKind(rv, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr)
+}
+
+func isNumber(rv reflect.Value) bool {
+	return isInt(rv) || isUint(rv) || isKind(rv, reflect.Float32, reflect.Float64)
+}
+
+func toFloat(rv reflect.Value) float64 {
+	switch {
+	case isInt(rv):
+		return float64(rv.Int())
+	case isUint(rv):
+		return float64(rv.Uint())
+	}
+	return rv.Float()
+}
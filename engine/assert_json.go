@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jimmyseraph/sparkle/utils/jsonpath"
+	"github.com/jimmyseraph/sparkle/utils/jsonschema"
+)
+
+// The AssertJSONPath methods query a JSON body, such as the Body of an
+// easy_http response, with a JSONPath expression like "$.data.items[0].id"
+// and name that path in the failure Detail.
+
+// AssertJSONPath checks the value at path. expected is compared in its JSON
+// form, so 1 matches the number 1 and structs match objects by their json
+// field names. Numbers compare exactly, also beyond the precision of a
+// float64.
+func (a *Assertion) AssertJSONPath(body string, path string, expected interface{}, title string) bool {
+	actual, ok := a.queryJSON(body, path, title)
+	if !ok {
+		return false
+	}
+	data, err := json.Marshal(expected)
+	if err != nil {
+		return a.failf("%s cannot compare %s with %T: %s", title, path, expected, err.Error())
+	}
+	normalized, _ := jsonpath.Decode(data)
+	if diffs := deepEqual(normalized, actual, newEqualConfig(nil)); len(diffs) > 0 {
+		return a.failf("%s expected %s at %s, but actual was %s", title, data, path, encodeJSON(actual))
+	}
+	return true
+}
+
+// AssertJSONPathType checks the JSON type at path: "null", "boolean",
+// "number", "string", "array" or "object".
+func (a *Assertion) AssertJSONPathType(body string, path string, jsonType string, title string) bool {
+	actual, ok := a.queryJSON(body, path, title)
+	if !ok {
+		return false
+	}
+	if actualType := jsonpath.TypeOf(actual); actualType != jsonType {
+		return a.failf("%s expected %s at %s, but actual was %s %s", title, jsonType, path, actualType, encodeJSON(actual))
+	}
+	return true
+}
+
+func (a *Assertion) AssertJSONPathExists(body string, path string, title string) bool {
+	_, ok := a.queryJSON(body, path, title)
+	return ok
+}
+
+func (a *Assertion) AssertJSONPathNotExists(body string, path string, title string) bool {
+	actual, err := jsonpath.GetJSON([]byte(body), path)
+	if err != nil && !errors.Is(err, jsonpath.ErrNotFound) {
+		return a.failf("%s %s", title, err.Error())
+	}
+	if err != nil {
+		return true
+	}
+	if values, ok := actual.([]interface{}); ok && len(values) == 0 {
+		if p, _ := jsonpath.Compile(path); !p.Definite() {
+			return true
+		}
+	}
+	return a.failf("%s expected nothing at %s, but actual was %s", title, path, encodeJSON(actual))
+}
+
+// AssertJSONPathLen checks the length of the array, object or string at path.
+func (a *Assertion) AssertJSONPathLen(body string, path string, length int, title string) bool {
+	actual, ok := a.queryJSON(body, path, title)
+	if !ok {
+		return false
+	}
+	actualLength, ok := lengthOf(actual)
+	if !ok {
+		return a.failf("%s expected array at %s, but actual was %s %s", title, path, jsonpath.TypeOf(actual), encodeJSON(actual))
+	}
+	if actualLength != length {
+		return a.failf("%s expected length %d at %s, but actual was %d", title, length, path, actualLength)
+	}
+	return true
+}
+
+// AssertJSONSchema validates body against a JSON Schema, given either as
+// the path of a schema file or inline. Every violation is recorded as a
+// separate Detail naming its JSON pointer.
+func (a *Assertion) AssertJSONSchema(schema string, body string, title string) bool {
+	var compiled *jsonschema.Schema
+	var err error
+	if inline := strings.TrimSpace(schema); strings.HasPrefix(inline, "{") || inline == "true" || inline == "false" {
+		compiled, err = jsonschema.Compile([]byte(inline))
+	} else {
+		compiled, err = jsonschema.CompileFile(schema)
+	}
+	if err != nil {
+		return a.failf("%s %s", title, err.Error())
+	}
+	violations, err := compiled.ValidateJSON([]byte(body))
+	if err != nil {
+		return a.failf("%s %s", title, err.Error())
+	}
+	for _, violation := range violations {
+		a.failf("%s schema violation at %s", title, violation.String())
+	}
+	return len(violations) == 0
+}
+
+// queryJSON returns the value at path, failing the Assertion when the body
+// is no JSON, the path is invalid or selects nothing.
+func (a *Assertion) queryJSON(body string, path string, title string) (interface{}, bool) {
+	actual, err := jsonpath.GetJSON([]byte(body), path)
+	if errors.Is(err, jsonpath.ErrNotFound) {
+		return nil, a.failf("%s expected a value at %s, but there was none", title, path)
+	}
+	if err != nil {
+		return nil, a.failf("%s %s", title, err.Error())
+	}
+	if values, ok := actual.([]interface{}); ok && len(values) == 0 {
+		if p, _ := jsonpath.Compile(path); !p.Definite() {
+			return nil, a.failf("%s expected a value at %s, but there was none", title, path)
+		}
+	}
+	return actual, true
+}
+
+func encodeJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestAsserts(t *testing.T) {
+	var nilMap map[string]int
+	var pathErr *fs.PathError
+	wrapped := fmt.Errorf("open: %w", os.ErrNotExist)
+
+	tests := []struct {
+		name  string
+		check func(a *Assertion) bool
+		want  bool
+	}{
+		{"true", func(a *Assertion) bool { return a.AssertTrue(true, "") }, true},
+		{"false", func(a *Assertion) bool { return a.AssertFalse(true, "") }, false},
+		{"nil typed", func(a *Assertion) bool { return a.AssertNil(nilMap, "") }, true},
+		{"not nil", func(a *Assertion) bool { return a.AssertNotNil(nil, "") }, false},
+		{"contains string", func(a *Assertion) bool { return a.AssertContains("sparkle", "ark", "") }, true},
+		{"contains slice", func(a *Assertion) bool { return a.AssertContains([]int{1, 2}, 3, "") }, false},
+		{"contains key", func(a *Assertion) bool { return a.AssertContains(map[string]int{"a": 1}, "a", "") }, true},
+		{"greater mixed", func(a *Assertion) bool { return a.AssertGreater(2.5, 2, "") }, true},
+		{"less strings", func(a *Assertion) bool { return a.AssertLess("b", "a", "") }, false},
+		{"greater invalid", func(a *Assertion) bool { return a.AssertGreater("a", 1, "") }, false},
+		{"in delta", func(a *Assertion) bool { return a.AssertInDelta(1.0, 1.05, 0.1, "") }, true},
+		{"regex", func(a *Assertion) bool { return a.AssertMatchesRegex(`^\d+$`, "12a", "") }, false},
+		{"error is", func(a *Assertion) bool { return a.AssertErrorIs(wrapped, os.ErrNotExist, "") }, true},
+		{"error as", func(a *Assertion) bool { return a.AssertErrorAs(wrapped, &pathErr, "") }, false},
+		{"error as bad target", func(a *Assertion) bool { return a.AssertErrorAs(errors.New("x"), pathErr, "") }, false},
+		{"len", func(a *Assertion) bool { return a.AssertLen([]string{"a"}, 1, "") }, true},
+		{"empty struct", func(a *Assertion) bool { return a.AssertEmpty(struct{ A int }{}, "") }, true},
+		{"empty string", func(a *Assertion) bool { return a.AssertEmpty("x", "") }, false},
+		{"panics", func(a *Assertion) bool { return a.AssertPanics(func() { panic("x") }, "") }, true},
+	}
+	for _, test := range tests {
+		a := NewAssertion(test.name, TEST_CASE, nil, nopLogger{})
+		if got := test.check(a); got != test.want {
+			t.Errorf("%s: got %v, want %v, details %v", test.name, got, test.want, a.GetDetails())
+		}
+		if failed := a.Result() == FAIL; failed == test.want || failed != (len(a.GetDetails()) == 1) {
+			t.Errorf("%s: result %v with details %v", test.name, a.Result(), a.GetDetails())
+		}
+	}
+}
+
+type item struct {
+	ID    int
+	Tags  []string
+	Price float64
+	Next  *item
+}
+
+func TestAssertEqualsDeep(t *testing.T) {
+	expected := map[string]interface{}{
+		"items": []item{{ID: 1, Tags: []string{"a", "b"}, Price: 1.5, Next: &item{ID: 2}}},
+		"total": 1,
+	}
+	tests := []struct {
+		name    string
+		actual  interface{}
+		options []EqualOption
+		want    bool
+		detail  string
+	}{
+		{"equal", map[string]interface{}{
+			"items": []item{{ID: 1, Tags: []string{"a", "b"}, Price: 1.5, Next: &item{ID: 2}}},
+			"total": 1,
+		}, nil, true, ""},
+		{"nested pointer", map[string]interface{}{
+			"items": []item{{ID: 1, Tags: []string{"a", "b"}, Price: 1.5, Next: &item{ID: 3}}},
+			"total": 1,
+		}, nil, false, `["items"][0].Next.ID: expected int(2), but actual was int(3)`},
+		{"ignored and unordered", map[string]interface{}{
+			"items": []item{{ID: 9, Tags: []string{"b", "a"}, Price: 1.5000001, Next: &item{ID: 2}}},
+			"total": 1,
+		}, []EqualOption{IgnoreFields("ID"), IgnoreOrder(), FloatTolerance(0.001)}, true, ""},
+		{"missing key", map[string]interface{}{
+			"items": []item{{ID: 1, Tags: []string{"a", "b"}, Price: 1.5, Next: &item{ID: 2}}},
+		}, nil, false, `["total"]: expected int(1), but it was missing`},
+	}
+	for _, test := range tests {
+		a := NewAssertion(test.name, TEST_CASE, nil, nopLogger{})
+		if got := a.AssertEquals(expected, test.actual, test.name, test.options...); got != test.want {
+			t.Errorf("%s: got %v, want %v, details %v", test.name, got, test.want, a.GetDetails())
+		}
+		if test.detail != "" && !hasDetail(a, ASSERT, test.detail) {
+			t.Errorf("%s: detail %q not found in %v", test.name, test.detail, a.GetDetails())
+		}
+	}
+
+	a := NewAssertion("nil", TEST_CASE, nil, nopLogger{})
+	var nilItem *item
+	if a.AssertEquals(nil, nilItem, "typed nil") || !a.AssertEquals(nilItem, nilItem, "same typed nil") {
+		t.Errorf("nil comparison: %v", a.GetDetails())
+	}
+	if !a.AssertNotEquals([]int{1}, []int{2}, "slices") || a.AssertNotEquals([]int{1}, []int{1}, "slices") {
+		t.Errorf("AssertNotEquals on slices: %v", a.GetDetails())
+	}
+
+	type pair struct {
+		A *item
+		B int
+	}
+	p, q := &item{ID: 1}, &item{ID: 2}
+	a = NewAssertion("shared pointers", TEST_CASE, nil, nopLogger{})
+	if a.AssertEquals([]pair{{A: p, B: 1}, {A: p, B: 2}}, []pair{{A: q, B: 2}, {A: q, B: 1}}, "shared pointers", IgnoreOrder()) ||
+		!hasDetail(a, ASSERT, "[0]: expected engine.pair, but no such element was found") {
+		t.Errorf("pointers a probe found different compared equal later: %v", a.GetDetails())
+	}
+}
+
+func TestAssertJSONPath(t *testing.T) {
+	body := `{"data": {"items": [{"id": 1, "name": "a"}, {"id": 2, "name": null}], "page": {"size": 2}}}`
+	tests := []struct {
+		name   string
+		check  func(a *Assertion) bool
+		want   bool
+		detail string
+	}{
+		{"value", func(a *Assertion) bool { return a.AssertJSONPath(body, "$.data.items[0].id", 1, "") }, true, ""},
+		{"object", func(a *Assertion) bool {
+			return a.AssertJSONPath(body, "$.data.page", map[string]int{"size": 2}, "")
+		}, true, ""},
+		{"wrong value", func(a *Assertion) bool { return a.AssertJSONPath(body, "$.data.items[1].id", 1, "") }, false,
+			"expected 1 at $.data.items[1].id, but actual was 2"},
+		{"large integer", func(a *Assertion) bool {
+			return a.AssertJSONPath(`{"id": 9007199254740993}`, "$.id", int64(9007199254740992), "")
+		}, false, "expected 9007199254740992 at $.id, but actual was 9007199254740993"},
+		{"same number", func(a *Assertion) bool { return a.AssertJSONPath(`{"price": 2.50}`, "$.price", 2.5, "") }, true, ""},
+		{"type", func(a *Assertion) bool { return a.AssertJSONPathType(body, "$.data.items[1].name", "null", "") }, true, ""},
+		{"exists", func(a *Assertion) bool { return a.AssertJSONPathExists(body, "$.data.total", "") }, false,
+			"expected a value at $.data.total"},
+		{"not exists", func(a *Assertion) bool { return a.AssertJSONPathNotExists(body, "$..total", "") }, true, ""},
+		{"empty array exists", func(a *Assertion) bool {
+			return a.AssertJSONPathNotExists(`{"items": []}`, "$.items", "")
+		}, false, "expected nothing at $.items, but actual was []"},
+		{"len", func(a *Assertion) bool { return a.AssertJSONPathLen(body, "$.data.items", 3, "") }, false,
+			"expected length 3 at $.data.items, but actual was 2"},
+		{"invalid body", func(a *Assertion) bool { return a.AssertJSONPathExists("<html>", "$.data", "") }, false, "invalid json"},
+	}
+	for _, test := range tests {
+		a := NewAssertion(test.name, TEST_CASE, nil, nopLogger{})
+		if got := test.check(a); got != test.want {
+			t.Errorf("%s: got %v, want %v, details %v", test.name, got, test.want, a.GetDetails())
+		}
+		if test.detail != "" && !hasDetail(a, ASSERT, test.detail) {
+			t.Errorf("%s: detail %q not found in %v", test.name, test.detail, a.GetDetails())
+		}
+	}
+}
+
+func TestAssertJSONSchema(t *testing.T) {
+	schema := `{"type": "object", "required": ["id"], "properties": {"id": {"type": "integer"}, "tags": {"items": {"type": "string"}}}}`
+	a := NewAssertion("schema", TEST_CASE, nil, nopLogger{})
+	if !a.AssertJSONSchema(schema, `{"id": 1, "tags": ["a"]}`, "user") {
+		t.Fatalf("valid body failed: %v", a.GetDetails())
+	}
+	if a.AssertJSONSchema(schema, `{"tags": ["a", 2]}`, "user") {
+		t.Fatal("invalid body passed")
+	}
+	if len(a.GetDetails()) != 2 || !hasDetail(a, ASSERT, `user schema violation at (root): missing required property "id"`) ||
+		!hasDetail(a, ASSERT, "user schema violation at /tags/1: expected string, but actual was number") {
+		t.Fatalf("violations not recorded one per Detail: %v", a.GetDetails())
+	}
+}
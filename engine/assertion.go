@@ -1,19 +1,38 @@
 package engine
 
 import (
+	"context"
 	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 	"time"
 )
 
 type Assertion struct {
+	mu       sync.Mutex
 	name     string
 	nodeType NodeType
 	parent   *Assertion
 	children []*Assertion
 	details  []Detail
 	result   Result
-	Logger   Logger
+	tags     []string
+	attempts int
+	ctx      context.Context
+	events   EventHandler
+	start    time.Time
+	end      time.Time
+	hooks    []Timing
+	fixtures *Fixtures
+	cleanups []func()
+	// faults counts the failures and crashes marked on the node, so that a
+	// hook failing on a node that failed already is told apart.
+	faults int
+	// sealed is set once the node finished or was replaced for a retry,
+	// after which writes from code that outlived its timeout are dropped.
+	sealed bool
+	Logger Logger
 }
 
 type Detail struct {
@@ -22,6 +41,20 @@ type Detail struct {
 	RecordTime time.Time
 }
 
+// Timing is when a hook started and ended, and how it went: ERROR when it
+// panicked or timed out, FAIL when it failed an assert, SKIPPED when it
+// called Skip and PASS otherwise.
+type Timing struct {
+	Name   string
+	Start  time.Time
+	End    time.Time
+	Result Result
+}
+
+func (t Timing) Duration() time.Duration {
+	return t.End.Sub(t.Start)
+}
+
 func NewAssertion(name string, nodeType NodeType, parent *Assertion, logger Logger) *Assertion {
 	assertion := &Assertion{
 		name:     name,
@@ -32,137 +65,390 @@ func NewAssertion(name string, nodeType NodeType, parent *Assertion, logger Logg
 		details:  make([]Detail, 0),
 		Logger:   logger,
 	}
+	assertion.fixtures = &Fixtures{owner: assertion}
 	if assertion.parent != nil {
+		assertion.parent.mu.Lock()
 		assertion.parent.children = append(assertion.parent.children, assertion)
+		assertion.parent.mu.Unlock()
 	}
 
 	return assertion
 }
 
-func (a *Assertion) AssertEquals(expected interface{}, actual interface{}, title string) {
-	if reflect.TypeOf(expected).Kind() != reflect.TypeOf(actual).Kind() {
-		a.details = append(a.details, Detail{
-			Name:       "Assert",
-			Message:    fmt.Sprintf("%s expected %T(%v), but actual was %T(%v)", title, expected, expected, actual, actual),
-			RecordTime: time.Now(),
-		})
-		a.fail()
+// AssertEquals checks that expected and actual are deeply equal: pointers
+// are followed, and maps, structs and slices are compared element by
+// element. On failure every differing path and a line diff of both values
+// are recorded.
+func (a *Assertion) AssertEquals(expected interface{}, actual interface{}, title string, options ...EqualOption) bool {
+	config := newEqualConfig(options)
+	diffs := deepEqual(expected, actual, config)
+	if len(diffs) == 0 {
+		return true
+	}
+	expectedLines := render(reflect.ValueOf(expected), config)
+	actualLines := render(reflect.ValueOf(actual), config)
+	if len(expectedLines) == 1 && len(actualLines) == 1 {
+		return a.failf("%s expected %T(%v), but actual was %T(%v)", title, expected, expected, actual, actual)
+	}
+	return a.failf("%s expected %T, but actual was %T with %d difference(s):\n  %s\n%s",
+		title, expected, actual, len(diffs), strings.Join(diffs, "\n  "), diffLines(expectedLines, actualLines))
+}
+
+// AssertNotEquals checks that expected and actual are not deeply equal, as
+// defined by AssertEquals.
+func (a *Assertion) AssertNotEquals(expected interface{}, actual interface{}, title string, options ...EqualOption) bool {
+	if len(deepEqual(expected, actual, newEqualConfig(options))) == 0 {
+		return a.failf("%s expected not %T(%v), but actual was %T(%v)", title, expected, expected, actual, actual)
+	}
+	return true
+}
+
+func (a *Assertion) AssertFail(title string) {
+	a.appendDetail(Detail{
+		Name:       "Assert",
+		Message:    fmt.Sprintf("Fail, because %s", title),
+		RecordTime: time.Now(),
+	})
+	a.fail()
+}
+
+// fail marks a as failed, unless it crashed already. The failure reaches
+// the parents once the runner settles a, so that a retried case which
+// passes in the end leaves its feature untouched.
+func (a *Assertion) fail() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.sealed {
 		return
 	}
-	switch reflect.TypeOf(expected).Kind() {
-	case reflect.Array, reflect.Slice:
-		expectedRV := reflect.ValueOf(expected)
-		actualRV := reflect.ValueOf(actual)
-		if expectedRV.Len() != actualRV.Len() {
-			a.details = append(a.details, Detail{
-				Name:       "Assert",
-				Message:    fmt.Sprintf("%s expected %T(%v), but actual was %T(%v)", title, expected, expected, actual, actual),
-				RecordTime: time.Now(),
-			})
-			a.fail()
-			return
-		}
-		for i := 0; i < expectedRV.Len(); i++ {
-			if expectedRV.Index(i).CanInterface() {
-				if expectedRV.Index(i).Interface() != actualRV.Index(i).Interface() {
-					a.details = append(a.details, Detail{
-						Name:       "Assert",
-						Message:    fmt.Sprintf("%s expected %T(%v), but actual was %T(%v)", title, expected, expected, actual, actual),
-						RecordTime: time.Now(),
-					})
-					a.fail()
-					return
-				}
-			} else {
-				a.details = append(a.details, Detail{
-					Name:       "Assert",
-					Message:    "cannot be compared",
-					RecordTime: time.Now(),
-				})
-				a.fail()
-				return
-			}
-		}
-	default:
-		if expected != actual {
-			a.details = append(a.details, Detail{
-				Name:       "Assert",
-				Message:    fmt.Sprintf("%s expected %T(%v), but actual was %T(%v)", title, expected, expected, actual, actual),
-				RecordTime: time.Now(),
-			})
-			a.fail()
-		}
+	a.faults++
+	if a.result != ERROR {
+		a.result = FAIL
+	}
+}
+
+// crash marks a as errored: it panicked or timed out rather than failed an
+// assert.
+func (a *Assertion) crash() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.sealed {
+		a.faults++
+		a.result = ERROR
 	}
+}
 
+// faultCount returns how many failures and crashes were marked on a.
+func (a *Assertion) faultCount() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.faults
 }
 
-func (a *Assertion) AssertNotEquals(expected interface{}, actual interface{}, title string) {
-	if reflect.TypeOf(expected).Kind() != reflect.TypeOf(actual).Kind() {
+// settle propagates a failure or a crash of a to all of its ancestors, a
+// crash taking precedence over a failure.
+func (a *Assertion) settle() {
+	result := a.Result()
+	if !result.Failed() {
 		return
 	}
-	switch reflect.TypeOf(expected).Kind() {
-	case reflect.Array, reflect.Slice:
-		expectedRV := reflect.ValueOf(expected)
-		actualRV := reflect.ValueOf(actual)
-		if expectedRV.Len() != actualRV.Len() {
-			return
+	for parent := a.parent; parent != nil; parent = parent.parent {
+		parent.mu.Lock()
+		if result == ERROR || parent.result != ERROR {
+			parent.result = result
 		}
-		for i := 0; i < expectedRV.Len(); i++ {
-			if expectedRV.Index(i).CanInterface() {
-				if expectedRV.Index(i).Interface() != actualRV.Index(i).Interface() {
-					return
-				}
-			} else {
-				a.details = append(a.details, Detail{
-					Name:       "Assert",
-					Message:    "cannot be compared",
-					RecordTime: time.Now(),
-				})
-				a.fail()
+		parent.mu.Unlock()
+	}
+}
+
+// conclude gives a node that ran without failing its final result: PASS for
+// a case. A feature or a suite passes when any of its cases passed or it
+// has none, and otherwise ends IGNORE when all of its cases were ignored
+// and SKIPPED when they were skipped.
+func (a *Assertion) conclude() {
+	if a.Result() != NOTRUN {
+		return
+	}
+	result := PASS
+	if a.nodeType != TEST_CASE {
+		if counts := a.Counts(); counts.Total > 0 && counts.Pass == 0 {
+			result = SKIPPED
+			if counts.Ignore == counts.Total {
+				result = IGNORE
 			}
 		}
-		a.details = append(a.details, Detail{
-			Name:       "Assert",
-			Message:    fmt.Sprintf("%s expected not %T(%v), but actual was %T(%v)", title, expected, expected, actual, actual),
-			RecordTime: time.Now(),
-		})
-		a.fail()
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.result == NOTRUN {
+		a.result = result
+	}
+}
+
+// Counts are the results of the cases under a node.
+type Counts struct {
+	Total   int
+	Pass    int
+	Fail    int
+	Error   int
+	Skipped int
+	Ignore  int
+	NotRun  int
+}
+
+func (c *Counts) add(result Result) {
+	c.Total++
+	switch result {
+	case PASS:
+		c.Pass++
+	case FAIL:
+		c.Fail++
+	case ERROR:
+		c.Error++
+	case SKIPPED:
+		c.Skipped++
+	case IGNORE:
+		c.Ignore++
 	default:
-		if expected == actual {
-			a.details = append(a.details, Detail{
-				Name:       "Assert",
-				Message:    fmt.Sprintf("%s expected not %T(%v), but actual was %T(%v)", title, expected, expected, actual, actual),
-				RecordTime: time.Now(),
-			})
-			a.fail()
-		}
+		c.NotRun++
 	}
 }
 
-func (a *Assertion) AssertFail(title string) {
-	a.details = append(a.details, Detail{
-		Name:       "Assert",
-		Message:    fmt.Sprintf("Fail, because %s", title),
-		RecordTime: time.Now(),
-	})
-	a.fail()
+// Counts returns the results of the cases of a suite or a feature, or of a
+// case itself.
+func (a *Assertion) Counts() Counts {
+	var counts Counts
+	if a.nodeType == TEST_CASE {
+		counts.add(a.Result())
+		return counts
+	}
+	for _, child := range a.Children() {
+		childCounts := child.Counts()
+		counts.Total += childCounts.Total
+		counts.Pass += childCounts.Pass
+		counts.Fail += childCounts.Fail
+		counts.Error += childCounts.Error
+		counts.Skipped += childCounts.Skipped
+		counts.Ignore += childCounts.Ignore
+		counts.NotRun += childCounts.NotRun
+	}
+	return counts
 }
 
-func (a *Assertion) fail() {
+func (a *Assertion) setResult(result Result) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.sealed {
+		a.result = result
+	}
+}
+
+// Context returns the context of the running hook or case. It is cancelled
+// once that call exceeds its timeout, so pass it on to easy_http, easy_grpc
+// and easy_db calls made on its behalf.
+func (a *Assertion) Context() context.Context {
+	a.mu.Lock()
+	ctx := a.ctx
+	a.mu.Unlock()
+	if ctx != nil {
+		return ctx
+	}
+	return a.parentContext()
+}
+
+// parentContext is the context a inherits from its parent, from which each
+// hook or case of a with a timeout derives its own.
+func (a *Assertion) parentContext() context.Context {
 	if a.parent != nil {
-		a.parent.fail()
+		return a.parent.Context()
 	}
-	a.result = FAIL
+	return context.Background()
+}
+
+func (a *Assertion) swapContext(ctx context.Context) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ctx = ctx
 }
 
 func (a *Assertion) Result() Result {
+	a.mu.Lock()
+	defer a.mu.Unlock()
 	return a.result
 }
 
+// Attempts returns how often the case was executed, more than one when it
+// was retried.
+func (a *Assertion) Attempts() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.attempts
+}
+
+// Flaky reports whether the case passed only after being retried.
+func (a *Assertion) Flaky() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.attempts > 1 && !a.result.Failed()
+}
+
+// StartTime returns when the node started running: the first attempt of a
+// case, the feature with its BeforeAll, or the suite.
+func (a *Assertion) StartTime() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.start
+}
+
+// EndTime returns when the node ended, after the last attempt of a case or
+// the AfterAll of a feature. It is zero while the node is running.
+func (a *Assertion) EndTime() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.end
+}
+
+// Duration returns how long the node ran, including its hooks, and how long
+// it has been running so far while it has not ended.
+func (a *Assertion) Duration() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch {
+	case a.start.IsZero():
+		return 0
+	case a.end.IsZero():
+		return time.Since(a.start)
+	}
+	return a.end.Sub(a.start)
+}
+
+// HookTimings returns the timings of the hooks run for the node in order:
+// BeforeAll and AfterAll on a feature, BeforeEach and AfterEach on a case,
+// once per attempt.
+func (a *Assertion) HookTimings() []Timing {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	hooks := make([]Timing, len(a.hooks))
+	copy(hooks, a.hooks)
+	return hooks
+}
+
+func (a *Assertion) addHookTiming(timing Timing) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.sealed {
+		a.hooks = append(a.hooks, timing)
+	}
+}
+
+func (a *Assertion) setAttempts(attempts int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.attempts = attempts
+}
+
+// Tags returns the tags of the TestCase the Assertion was created for,
+// including those inherited from its feature.
+func (a *Assertion) Tags() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	tags := make([]string, len(a.tags))
+	copy(tags, a.tags)
+	return tags
+}
+
+func (a *Assertion) setTags(tags []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tags = tags
+}
+
 func (a *Assertion) AddDetail(name string, message string, args ...interface{}) {
-	a.details = append(a.details, Detail{Name: name, Message: fmt.Sprintf(message, args...), RecordTime: time.Now()})
+	a.appendDetail(Detail{Name: name, Message: fmt.Sprintf(message, args...), RecordTime: time.Now()})
+}
+
+// Capture records text verbatim as a Detail, unlike AddDetail which treats
+// its message as a format. Use it for dumps, which may contain %, as in
+//
+//	assertion.Capture(engine.HTTP_REQUEST, req.Dump())
+func (a *Assertion) Capture(name string, text string) {
+	a.appendDetail(Detail{Name: name, Message: text, RecordTime: time.Now()})
+}
+
+func (a *Assertion) appendDetail(detail Detail) {
+	a.mu.Lock()
+	sealed := a.sealed
+	if !sealed {
+		a.details = append(a.details, detail)
+	}
+	a.mu.Unlock()
+	if !sealed {
+		a.emit(Event{Type: EVENT_DETAIL, Detail: &detail})
+	}
+}
+
+// renew replaces a in the tree by a fresh node for the next attempt of a
+// retried case. The fresh node carries the Details, hook timings and
+// attempts recorded so far, while a is sealed, so that an earlier attempt
+// still running after its timeout neither changes the result of the retry
+// nor sees its context revived.
+func (a *Assertion) renew() *Assertion {
+	a.mu.Lock()
+	fresh := &Assertion{
+		name:     a.name,
+		nodeType: a.nodeType,
+		result:   NOTRUN,
+		parent:   a.parent,
+		children: make([]*Assertion, 0),
+		details:  append(make([]Detail, 0, len(a.details)), a.details...),
+		tags:     a.tags,
+		attempts: a.attempts,
+		start:    a.start,
+		hooks:    append([]Timing(nil), a.hooks...),
+		Logger:   a.Logger,
+	}
+	a.sealed = true
+	a.mu.Unlock()
+	fresh.fixtures = &Fixtures{owner: fresh}
+	if a.parent != nil {
+		a.parent.mu.Lock()
+		for i, child := range a.parent.children {
+			if child == a {
+				a.parent.children[i] = fresh
+			}
+		}
+		a.parent.mu.Unlock()
+	}
+	return fresh
 }
 
 func (a *Assertion) GetDetails() []Detail {
-	return a.details
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	details := make([]Detail, len(a.details))
+	copy(details, a.details)
+	return details
+}
+
+func (a *Assertion) Name() string {
+	return a.name
+}
+
+func (a *Assertion) NodeType() NodeType {
+	return a.nodeType
+}
+
+// Parent returns the enclosing feature of a case, the suite of a feature,
+// and nil for the root.
+func (a *Assertion) Parent() *Assertion {
+	return a.parent
+}
+
+// Children returns the features of a suite or the cases of a feature.
+func (a *Assertion) Children() []*Assertion {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	children := make([]*Assertion, len(a.children))
+	copy(children, a.children)
+	return children
 }
@@ -0,0 +1,52 @@
+package engine
+
+// Cleanup registers fn to tear down a resource when the scope of the
+// Assertion ends: after AfterEach for a case, after AfterAll for a feature
+// or a suite. Cleanups run in the reverse order of registration and always
+// run, even when the scope failed, panicked or timed out. A cleanup that
+// fails an assert or panics is recorded as a Detail on the Assertion, fails
+// or crashes it, and does not stop the other cleanups.
+//
+// A case that is retried runs its cleanups after every attempt.
+func (a *Assertion) Cleanup(fn func()) {
+	if fn == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.sealed {
+		a.cleanups = append(a.cleanups, fn)
+	}
+}
+
+// runCleanups runs the registered cleanups of a, the last registered first,
+// timed as a single Cleanup hook. It reports whether any of them panicked.
+func (a *Assertion) runCleanups() (crashed bool) {
+	a.mu.Lock()
+	pending := len(a.cleanups)
+	a.mu.Unlock()
+	if pending == 0 {
+		return false
+	}
+	before := a.Result()
+	callHook(a, "Cleanup", 0, func() {
+		for {
+			a.mu.Lock()
+			n := len(a.cleanups)
+			if n == 0 {
+				a.mu.Unlock()
+				return
+			}
+			fn := a.cleanups[n-1]
+			a.cleanups = a.cleanups[:n-1]
+			a.mu.Unlock()
+			if protect(a, "Cleanup", fn) {
+				crashed = true
+			}
+		}
+	})
+	if after := a.Result(); crashed || (after != before && after.Failed()) {
+		a.AddDetail(RESULT, "Cleanup failed on %s", a.name)
+	}
+	return crashed
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCleanup(t *testing.T) {
+	var mu sync.Mutex
+	var cleaned []string
+	cleanup := func(name string) func() {
+		return func() {
+			mu.Lock()
+			cleaned = append(cleaned, name)
+			mu.Unlock()
+		}
+	}
+	suite := &TestSuite{
+		Name: "suite",
+		BeforeAll: func(assertion *Assertion) {
+			assertion.Cleanup(cleanup("suite first"))
+			assertion.Cleanup(cleanup("suite last"))
+		},
+		Features: []*TestFeature{
+			{
+				Name: "feature",
+				BeforeAll: func(assertion *Assertion) {
+					assertion.Cleanup(cleanup("feature"))
+				},
+				AfterAll: func(assertion *Assertion) {
+					assertion.Cleanup(cleanup("registered in AfterAll"))
+				},
+				TestCases: []TestCase{
+					{
+						Name: "panics",
+						Case: func(assertion *Assertion, args ...interface{}) {
+							assertion.Cleanup(cleanup("case first"))
+							assertion.Cleanup(cleanup("case last"))
+							panic("boom")
+						},
+					},
+					{
+						Name: "cleanup fails",
+						Case: func(assertion *Assertion, args ...interface{}) {
+							assertion.Cleanup(func() { assertion.AssertTrue(false, "connection closed") })
+							assertion.Cleanup(func() { panic("cannot drop table") })
+						},
+					},
+				},
+			},
+			{
+				Name: "broken setup",
+				BeforeAll: func(assertion *Assertion) {
+					assertion.Cleanup(cleanup("broken setup"))
+					panic("no connection")
+				},
+				TestCases: []TestCase{
+					{Name: "never runs", Case: func(assertion *Assertion, args ...interface{}) {}},
+				},
+			},
+		},
+	}
+
+	summary := suite.Run()
+	features := summary.Root.Children()
+	cases := features[0].Children()
+	if cases[0].Result() != ERROR {
+		t.Errorf("panicking case ended %v", cases[0].Result())
+	}
+	failing := cases[1]
+	if failing.Result() != ERROR || !hasDetail(failing, PANIC, "Cleanup panicked: cannot drop table") ||
+		!hasDetail(failing, ASSERT, "connection closed") || !hasDetail(failing, RESULT, "Cleanup failed on cleanup fails") {
+		t.Errorf("failing cleanups recorded as %v: %v", failing.Result(), failing.GetDetails())
+	}
+	hooks := failing.HookTimings()
+	if len(hooks) != 1 || hooks[0].Name != "Cleanup" {
+		t.Errorf("unexpected case hook timings %+v", hooks)
+	}
+	if hasDetail(cases[0], RESULT, "Cleanup failed") {
+		t.Errorf("case failure blamed on its cleanups: %v", cases[0].GetDetails())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	got := strings.Join(cleaned, ",")
+	for _, want := range []string{
+		"case last,case first",
+		"registered in AfterAll,feature",
+		"broken setup",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("cleanups ran as %s, missing %s", got, want)
+		}
+	}
+	if !strings.HasSuffix(got, ",suite last,suite first") {
+		t.Errorf("suite cleanups did not run last: %s", got)
+	}
+}
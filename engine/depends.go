@@ -0,0 +1,168 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// prerequisite follows the executions of a case, one per Parameterize row,
+// so that the cases depending on it can wait for them.
+type prerequisite struct {
+	name    string
+	mu      sync.Mutex
+	pending int
+	result  Result
+	done    chan struct{}
+}
+
+func newPrerequisite(name string, executions int) *prerequisite {
+	p := &prerequisite{name: name, pending: executions, result: PASS, done: make(chan struct{})}
+	if executions == 0 {
+		close(p.done)
+	}
+	return p
+}
+
+// record notes the result of one execution. The prerequisite passes only
+// when every execution passed, otherwise it keeps the first other result.
+func (p *prerequisite) record(result Result) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.result == PASS && result != PASS {
+		p.result = result
+	}
+	p.pending--
+	if p.pending == 0 {
+		close(p.done)
+	}
+}
+
+// wait blocks until every execution has finished and returns the result.
+func (p *prerequisite) wait() Result {
+	<-p.done
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.result
+}
+
+// awaitPrerequisites waits for all prerequisites of job and returns why it
+// must be skipped, or an empty string when they all passed.
+func (job caseJob) awaitPrerequisites() string {
+	reason := ""
+	for _, p := range job.prerequisites {
+		if result := p.wait(); result != PASS && reason == "" {
+			reason = fmt.Sprintf("prerequisite %s ended %v", p.name, result)
+		}
+	}
+	return reason
+}
+
+// Selected returns the cases of the feature a run with filters executes:
+// those every filter selects along with the cases they depend on, in
+// declaration order.
+func (t *TestFeature) Selected(filters ...*Filter) []*TestCase {
+	testCases := t.cases()
+	return t.withPrerequisites(t.selected(testCases, runConfig{filters: filters}), testCases)
+}
+
+// withPrerequisites adds to the selected cases those they depend on,
+// directly or not, even when the filters left them out. Names are looked
+// up among the cases of the feature and testCases.
+func (t *TestFeature) withPrerequisites(selected []*TestCase, testCases []*TestCase) []*TestCase {
+	byName := caseNames(append(t.cases(), testCases...))
+	included := make(map[*TestCase]bool)
+	var include func(testCase *TestCase)
+	include = func(testCase *TestCase) {
+		if included[testCase] {
+			return
+		}
+		included[testCase] = true
+		for _, name := range testCase.DependsOn {
+			if prerequisite, ok := byName[name]; ok {
+				include(prerequisite)
+			}
+		}
+	}
+	for _, testCase := range selected {
+		include(testCase)
+	}
+
+	result := make([]*TestCase, 0, len(included))
+	seen := make(map[*TestCase]bool)
+	for _, testCase := range append(t.cases(), testCases...) {
+		if included[testCase] && !seen[testCase] {
+			seen[testCase] = true
+			result = append(result, testCase)
+		}
+	}
+	return result
+}
+
+// orderCases sorts testCases so that every case comes after the cases it
+// depends on, keeping the declaration order otherwise. Cases depending on
+// an unknown case, or on a cycle, are returned with the reason they cannot
+// run.
+func orderCases(testCases []*TestCase) ([]*TestCase, map[*TestCase]string) {
+	byName := caseNames(testCases)
+	problems := make(map[*TestCase]string)
+	for _, testCase := range testCases {
+		for _, name := range testCase.DependsOn {
+			if _, ok := byName[name]; !ok {
+				problems[testCase] = fmt.Sprintf("testcase %s depends on unknown testcase %s", testCase.Name, name)
+			}
+		}
+	}
+
+	ordered := make([]*TestCase, 0, len(testCases))
+	placed := make(map[*TestCase]bool)
+	for len(ordered) < len(testCases) {
+		progress := false
+		for _, testCase := range testCases {
+			if placed[testCase] || !dependenciesPlaced(testCase, byName, placed) {
+				continue
+			}
+			placed[testCase] = true
+			ordered = append(ordered, testCase)
+			progress = true
+			break
+		}
+		if !progress {
+			break
+		}
+	}
+
+	var cyclic []string
+	for _, testCase := range testCases {
+		if !placed[testCase] {
+			cyclic = append(cyclic, testCase.Name)
+		}
+	}
+	for _, testCase := range testCases {
+		if !placed[testCase] {
+			problems[testCase] = fmt.Sprintf("DependsOn forms a cycle among testcases %s", strings.Join(cyclic, ", "))
+			ordered = append(ordered, testCase)
+		}
+	}
+	return ordered, problems
+}
+
+func dependenciesPlaced(testCase *TestCase, byName map[string]*TestCase, placed map[*TestCase]bool) bool {
+	for _, name := range testCase.DependsOn {
+		if prerequisite, ok := byName[name]; ok && !placed[prerequisite] {
+			return false
+		}
+	}
+	return true
+}
+
+// caseNames maps names to cases, the first declared case winning.
+func caseNames(testCases []*TestCase) map[string]*TestCase {
+	byName := make(map[string]*TestCase, len(testCases))
+	for _, testCase := range testCases {
+		if _, ok := byName[testCase.Name]; !ok {
+			byName[testCase.Name] = testCase
+		}
+	}
+	return byName
+}
@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDependsOn(t *testing.T) {
+	var mu sync.Mutex
+	var ran []string
+	step := func(name string, pass bool) func(*Assertion, ...interface{}) {
+		return func(assertion *Assertion, args ...interface{}) {
+			time.Sleep(5 * time.Millisecond)
+			mu.Lock()
+			ran = append(ran, name)
+			mu.Unlock()
+			assertion.AssertTrue(pass, name)
+		}
+	}
+	feature := &TestFeature{
+		Name:     "flow",
+		Parallel: 4,
+		TestCases: []TestCase{
+			{Name: "delete", DependsOn: []string{"login"}, Case: step("delete", true)},
+			{Name: "login", DependsOn: []string{"create"}, Case: step("login", false), Tag: []string{"auth"}},
+			{Name: "create", Case: step("create", true)},
+			{Name: "report", DependsOn: []string{"delete"}, Case: step("report", true)},
+			{Name: "orphan", DependsOn: []string{"missing"}, Case: step("orphan", true)},
+			{Name: "ping", DependsOn: []string{"pong"}, Case: step("ping", true)},
+			{Name: "pong", DependsOn: []string{"ping"}, Case: step("pong", true)},
+		},
+	}
+
+	node := runFeature(t, feature)
+	var names []string
+	cases := make(map[string]*Assertion)
+	for _, testCase := range node.Children() {
+		names = append(names, testCase.Name())
+		cases[testCase.Name()] = testCase
+	}
+	if got := strings.Join(names, ","); got != "create,login,delete,report,orphan,ping,pong" {
+		t.Errorf("cases planned as %s", got)
+	}
+	if got := strings.Join(ran, ","); got != "create,login" {
+		t.Errorf("cases ran as %s", got)
+	}
+	if cases["create"].Result() != PASS || cases["login"].Result() != FAIL {
+		t.Errorf("prerequisites ended %v and %v", cases["create"].Result(), cases["login"].Result())
+	}
+	if testCase := cases["delete"]; testCase.Result() != SKIPPED || !hasDetail(testCase, SKIP, "prerequisite login ended Fail") {
+		t.Errorf("delete ended %v: %v", testCase.Result(), testCase.GetDetails())
+	}
+	if testCase := cases["report"]; testCase.Result() != SKIPPED || !hasDetail(testCase, SKIP, "prerequisite delete ended Skipped") {
+		t.Errorf("report ended %v: %v", testCase.Result(), testCase.GetDetails())
+	}
+	if testCase := cases["orphan"]; testCase.Result() != FAIL || !hasDetail(testCase, RESULT, "unknown testcase missing") {
+		t.Errorf("orphan ended %v: %v", testCase.Result(), testCase.GetDetails())
+	}
+	for _, name := range []string{"ping", "pong"} {
+		if testCase := cases[name]; testCase.Result() != FAIL || !hasDetail(testCase, RESULT, "cycle among testcases ping, pong") {
+			t.Errorf("%s ended %v: %v", name, testCase.Result(), testCase.GetDetails())
+		}
+	}
+
+	ran = nil
+	feature.TestCases[1].Case = step("login", true)
+	node = runFeature(t, feature, "auth")
+	names = names[:0]
+	for _, testCase := range node.Children() {
+		names = append(names, testCase.Name())
+	}
+	if got := strings.Join(names, ","); got != "create,login" || strings.Join(ran, ",") != "create,login" {
+		t.Errorf("filtered run planned %s and ran %s", got, strings.Join(ran, ","))
+	}
+}
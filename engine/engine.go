@@ -1,6 +1,11 @@
 package engine
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
 
 type TestFeature struct {
 	Name       string
@@ -9,6 +14,15 @@ type TestFeature struct {
 	BeforeEach func(assertion *Assertion)
 	AfterEach  func(assertion *Assertion)
 	TestCases  []TestCase
+	// Timeout bounds every case of the feature that does not set its own,
+	// as well as each BeforeAll and AfterAll call. Zero falls back to the
+	// suite setting.
+	Timeout time.Duration
+	// Parallel is the maximum number of cases run at the same time. Zero
+	// falls back to the suite setting, one runs the cases sequentially.
+	Parallel int
+	// Tags are inherited by every case of the feature.
+	Tags []string
 }
 
 type TestCase struct {
@@ -17,6 +31,24 @@ type TestCase struct {
 	Ignore       bool
 	Parameterize func() [][]interface{}
 	Case         func(assertion *Assertion, args ...interface{})
+	// Timeout bounds each of BeforeEach, the case and AfterEach.
+	Timeout time.Duration
+	// Retry runs BeforeEach, the case and AfterEach again when they fail.
+	Retry *RetryPolicy
+	// Serial keeps the case from running alongside any other case of its
+	// feature, even when the feature runs in parallel.
+	Serial bool
+	// RequireEnv names environment variables the case needs. When any of
+	// them is unset or empty, the case is skipped.
+	RequireEnv []string
+	// SkipIf is called once before the case is planned. When it returns
+	// true, the case is skipped with the returned reason.
+	SkipIf func() (bool, string)
+	// DependsOn names cases of the same feature that must pass before this
+	// one runs, every Parameterize row of them. The case is skipped when
+	// any of them does not pass. Prerequisites run even when filters leave
+	// them out.
+	DependsOn []string
 }
 
 const (
@@ -27,232 +59,528 @@ const (
 	STEP          = "Step"
 	RESULT        = "Result"
 	ASSERT        = "Assert"
+	PANIC         = "Panic"
+	TIMEOUT       = "Timeout"
+	ATTEMPT       = "Attempt"
+	SKIP          = "Skip"
+	// HTTP_REQUEST, HTTP_RESPONSE, GRPC_REQUEST and GRPC_RESPONSE name
+	// Details holding the dumps of an easy_http or easy_grpc exchange, which
+	// reports show as captures. Record them with Assertion.Capture.
+	HTTP_REQUEST  = "Http Request"
+	HTTP_RESPONSE = "Http Response"
+	GRPC_REQUEST  = "Grpc Request"
+	GRPC_RESPONSE = "Grpc Response"
 )
 
+// RunFeature runs the cases matching any of the tag expressions, see
+// NewFilter, or every case without tags. An invalid expression fails the
+// feature without running it.
 func (t *TestFeature) RunFeature(parent *Assertion, logger Logger, c chan *Assertion, tags ...string) {
-	node := NewAssertion(t.Name, TEST_FEATURE, parent, logger)
-	logger.Log(FEATURE_START, "Start running feature %s", t.Name)
-	if t.BeforeAll != nil {
-		logger.Log(STEP, "Running BeforeAll")
-		t.BeforeAll(node)
-	}
-	if x := recover(); x != nil {
+	filter, err := tagFilter(tags)
+	if err != nil {
+		node := NewAssertion(t.Name, TEST_FEATURE, parent, logger)
+		node.AddDetail(RESULT, "Cannot run feature %s: %s", t.Name, err.Error())
 		node.fail()
-		logger.Log(RESULT, "BeforeAll failed on feature %s", t.Name)
+		node.settle()
 		return
 	}
+	t.run(parent, logger, c, t.cases(), runConfig{filters: []*Filter{filter}})
+}
 
-	for _, testCase := range t.TestCases {
-		if !testCase.Ignore && testCase.Case != nil {
-			if tags != nil {
-				isMatch := false
-				for _, tag := range tags {
-					if isMatch {
-						break
-					}
-					for _, caseTag := range testCase.Tag {
-						if tag == caseTag {
-							isMatch = true
-							break
-						}
-					}
-				}
-				if !isMatch {
-					testNode := NewAssertion(testCase.Name, TEST_CASE, node, logger)
-					testNode.result = IGNORE
-					continue
-				}
-			}
+// RunFeatureFiltered runs the cases selected by every filter.
+func (t *TestFeature) RunFeatureFiltered(parent *Assertion, logger Logger, c chan *Assertion, filters ...*Filter) {
+	t.run(parent, logger, c, t.cases(), runConfig{filters: filters})
+}
 
-			if testCase.Parameterize != nil {
-				parameters := testCase.Parameterize()
-				for i, param := range parameters {
-					caseName := fmt.Sprintf("%s[%d]", testCase.Name, i)
-					testNode := NewAssertion(caseName, TEST_CASE, node, logger)
-
-					if t.BeforeEach != nil {
-						testNode.AddDetail(STEP, "Running BeforeEach before testcase %s", caseName)
-						t.BeforeEach(testNode)
-						if x := recover(); x != nil || testNode.result == FAIL {
-							testNode.fail()
-							testNode.AddDetail(RESULT, "BeforeEach failed before testcase %s", caseName)
-							c <- testNode
-							continue
-						}
-					}
-
-					testCase.runCase(caseName, testNode, param...)
-					if x := recover(); x != nil {
-						testNode.fail()
-						testNode.AddDetail(RESULT, "testcase %s failed", caseName)
-					}
-
-					if t.AfterEach != nil {
-						testNode.AddDetail(STEP, "Running AfterEach before testcase %s", caseName)
-						t.AfterEach(testNode)
-						if x := recover(); x != nil || testNode.result == FAIL {
-							testNode.fail()
-							testNode.AddDetail(RESULT, "AfterEach failed before testcase %s", caseName)
-							c <- testNode
-							continue
-						}
-					}
-					c <- testNode
-				}
-			} else {
-				testNode := NewAssertion(testCase.Name, TEST_CASE, node, logger)
-				if t.BeforeEach != nil {
-					testNode.AddDetail(STEP, "Running BeforeEach before testcase %s", testCase.Name)
-					t.BeforeEach(testNode)
-					if x := recover(); x != nil || testNode.result == FAIL {
-						testNode.fail()
-						testNode.AddDetail(RESULT, "BeforeEach failed before testcase %s", testCase.Name)
-						c <- testNode
-						continue
-					}
-				}
-
-				testCase.runCase(testCase.Name, testNode)
-				if x := recover(); x != nil {
-					testNode.fail()
-					testNode.AddDetail(RESULT, "testcase %s failed", testCase.Name)
-				}
-
-				if t.AfterEach != nil {
-					testNode.AddDetail(STEP, "Running AfterEach before testcase %s", testCase.Name)
-					t.AfterEach(testNode)
-					if x := recover(); x != nil || testNode.result == FAIL {
-						testNode.fail()
-						testNode.AddDetail(RESULT, "AfterEach failed before testcase %s", testCase.Name)
-						c <- testNode
-						continue
-					}
-				}
-				c <- testNode
-			}
-		} else {
-			testNode := NewAssertion(testCase.Name, TEST_CASE, node, logger)
-			testNode.result = IGNORE
-			c <- testNode
+// RunTestCase runs the given cases of the feature that every filter
+// selects.
+func (t *TestFeature) RunTestCase(testCases []*TestCase, parent *Assertion, c chan *Assertion, logger Logger, filters ...*Filter) {
+	t.run(parent, logger, c, testCases, runConfig{filters: filters})
+}
+
+// runConfig carries the options a suite hands down to its features.
+type runConfig struct {
+	filters  []*Filter
+	parallel int
+	timeout  time.Duration
+	// abort, when set, is why the suite did not run its features: they
+	// resolve their cases with the result of the suite instead.
+	abort string
+}
+
+// filtered reports whether any filter of config may leave cases out.
+func (c runConfig) filtered() bool {
+	for _, f := range c.filters {
+		if f != nil {
+			return true
 		}
 	}
-	if t.AfterAll != nil {
-		logger.Log(STEP, "Running AfterAll")
-		t.AfterAll(node)
-	}
-	if x := recover(); x != nil {
-		node.fail()
-		logger.Log(RESULT, "AfterAll failed on feature %s", t.Name)
+	return false
+}
+
+// caseJob is a single execution of a TestCase, one per Parameterize row.
+type caseJob struct {
+	testCase *TestCase
+	name     string
+	node     *Assertion
+	params   []interface{}
+	timeout  time.Duration
+	// prerequisites are the cases the execution waits for, and tracker
+	// follows the executions of its own case for those depending on it.
+	prerequisites []*prerequisite
+	tracker       *prerequisite
+	// resolved is set for a case that does not run, such as an ignored
+	// one, whose node already holds its result.
+	resolved bool
+}
+
+func (t *TestFeature) cases() []*TestCase {
+	testCases := make([]*TestCase, 0, len(t.TestCases))
+	for i := range t.TestCases {
+		testCases = append(testCases, &t.TestCases[i])
 	}
-	defer logger.Log(FEATURE_END, "End running feature %s", t.Name)
+	return testCases
 }
 
-func (t *TestFeature) RunTestCase(testCases []*TestCase, parent *Assertion, c chan *Assertion, logger Logger) {
+// selected returns the cases the filters of config select, in order.
+func (t *TestFeature) selected(testCases []*TestCase, config runConfig) []*TestCase {
+	selected := make([]*TestCase, 0, len(testCases))
+	for _, testCase := range testCases {
+		if matchAll(config.filters, t, testCase) {
+			selected = append(selected, testCase)
+		}
+	}
+	return selected
+}
 
+// run runs the selected cases along with the cases they depend on. A
+// feature whose cases the filters leave out entirely is not run, and gets
+// no node.
+func (t *TestFeature) run(parent *Assertion, logger Logger, c chan *Assertion, testCases []*TestCase, config runConfig) {
+	selected := t.selected(testCases, config)
+	if len(selected) == 0 && len(testCases) > 0 && config.filtered() {
+		return
+	}
+	testCases = t.withPrerequisites(selected, testCases)
 	node := NewAssertion(t.Name, TEST_FEATURE, parent, logger)
 	logger.Log(FEATURE_START, "Start running feature %s", t.Name)
+	defer logger.Log(FEATURE_END, "End running feature %s", t.Name)
+	node.begin(EVENT_FEATURE_START)
+	defer node.finish(EVENT_FEATURE_END)
+	jobs := t.plan(node, logger, c, testCases, config)
+	if config.abort != "" {
+		node.setResult(parent.Result())
+		t.resolve(jobs, c, parent.Result(), "%s", config.abort)
+		return
+	}
 	if t.BeforeAll != nil {
 		logger.Log(STEP, "Running BeforeAll")
-		t.BeforeAll(node)
+		crashed := callHook(node, "BeforeAll", t.timeout(config), func() { t.BeforeAll(node) })
+		if crashed || node.Result().Failed() {
+			logger.Log(RESULT, "BeforeAll failed on feature %s", t.Name)
+			t.resolve(jobs, c, node.Result(), "BeforeAll failed on feature %s", t.Name)
+			t.afterAll(node, logger, config)
+			node.settle()
+			return
+		}
+		if node.Result() == SKIPPED {
+			logger.Log(RESULT, "BeforeAll skipped feature %s", t.Name)
+			t.resolve(jobs, c, SKIPPED, "BeforeAll skipped feature %s", t.Name)
+			t.afterAll(node, logger, config)
+			node.settle()
+			return
+		}
 	}
-	if x := recover(); x != nil {
-		node.fail()
-		logger.Log(RESULT, "BeforeAll failed on feature %s", t.Name)
-		return
+
+	workers := t.Parallel
+	if workers == 0 {
+		workers = config.parallel
+	}
+	pool := newWorkerPool(workers)
+	for _, job := range jobs {
+		job := job
+		if job.resolved {
+			if workers <= 1 {
+				// keep a sequential feature's reports in declaration order
+				pool.Wait()
+			}
+			t.deliver(job, c)
+			continue
+		}
+		if job.testCase.Serial {
+			pool.Wait()
+			t.runJob(job, c)
+			continue
+		}
+		pool.Go(func() {
+			t.runJob(job, c)
+		})
+	}
+	pool.Wait()
+	t.afterAll(node, logger, config)
+	node.conclude()
+	node.settle()
+}
+
+// resolve hands the planned executions to the listener without running
+// them, giving those not resolved yet result and a Detail saying why.
+func (t *TestFeature) resolve(jobs []caseJob, c chan *Assertion, result Result, format string, args ...interface{}) {
+	for _, job := range jobs {
+		if !job.resolved {
+			job.node.AddDetail(RESULT, format, args...)
+			job.node.setResult(result)
+		}
+		t.deliver(job, c)
 	}
+}
 
+// deliver settles the node of a case that did not run and hands it to the
+// listener.
+func (t *TestFeature) deliver(job caseJob, c chan *Assertion) {
+	job.node.settle()
+	job.node.finish(EVENT_CASE_END)
+	job.tracker.record(job.node.Result())
+	c <- job.node
+}
+
+// afterAll runs AfterAll, then the cleanups of the feature's fixtures.
+func (t *TestFeature) afterAll(node *Assertion, logger Logger, config runConfig) {
+	if t.AfterAll != nil {
+		logger.Log(STEP, "Running AfterAll")
+		if callHook(node, "AfterAll", t.timeout(config), func() { t.AfterAll(node) }) {
+			logger.Log(RESULT, "AfterAll failed on feature %s", t.Name)
+		}
+	}
+	node.runCleanups()
+}
+
+// plan orders the cases after those they depend on and creates their
+// nodes in that order, so that the Assertion tree does not depend on the
+// order in which workers finish, and returns the executions in that order.
+// Ignored cases, cases whose RequireEnv or SkipIf rule them out and cases
+// with broken dependencies are resolved right away, and delivered when
+// their turn comes.
+// testCases holds the selected cases and their prerequisites only.
+func (t *TestFeature) plan(node *Assertion, logger Logger, c chan *Assertion, testCases []*TestCase, config runConfig) []caseJob {
+	byName := caseNames(testCases)
+	testCases, problems := orderCases(testCases)
+	tracked := make(map[*TestCase]*prerequisite, len(testCases))
+	jobs := make([]caseJob, 0, len(testCases))
 	for _, testCase := range testCases {
-		if !testCase.Ignore && testCase.Case != nil {
-			if testCase.Parameterize != nil {
-				parameters := testCase.Parameterize()
-				for i, param := range parameters {
-					caseName := fmt.Sprintf("%s[%d]", testCase.Name, i)
-					testNode := NewAssertion(caseName, TEST_CASE, node, logger)
-
-					if t.BeforeEach != nil {
-						testNode.AddDetail(STEP, "Running BeforeEach before testcase %s", caseName)
-						t.BeforeEach(testNode)
-						if x := recover(); x != nil || testNode.result == FAIL {
-							testNode.fail()
-							testNode.AddDetail(RESULT, "BeforeEach failed before testcase %s", caseName)
-							c <- testNode
-							continue
-						}
-					}
-
-					testCase.runCase(caseName, testNode, param...)
-					if x := recover(); x != nil {
-						testNode.fail()
-						testNode.AddDetail(RESULT, "testcase %s failed", caseName)
-					}
-
-					if t.AfterEach != nil {
-						testNode.AddDetail(STEP, "Running AfterEach before testcase %s", caseName)
-						t.AfterEach(testNode)
-						if x := recover(); x != nil || testNode.result == FAIL {
-							testNode.fail()
-							testNode.AddDetail(RESULT, "AfterEach failed before testcase %s", caseName)
-							c <- testNode
-							continue
-						}
-					}
-					c <- testNode
-				}
-			} else {
-				testNode := NewAssertion(testCase.Name, TEST_CASE, node, logger)
-				if t.BeforeEach != nil {
-					testNode.AddDetail(STEP, "Running BeforeEach before testcase %s", testCase.Name)
-					t.BeforeEach(testNode)
-					if x := recover(); x != nil || testNode.result == FAIL {
-						testNode.fail()
-						testNode.AddDetail(RESULT, "BeforeEach failed before testcase %s", testCase.Name)
-						c <- testNode
-						continue
-					}
-				}
-
-				testCase.runCase(testCase.Name, testNode)
-				if x := recover(); x != nil {
-					testNode.fail()
-					testNode.AddDetail(RESULT, "testcase %s failed", testCase.Name)
-				}
-
-				if t.AfterEach != nil {
-					testNode.AddDetail(STEP, "Running AfterEach before testcase %s", testCase.Name)
-					t.AfterEach(testNode)
-					if x := recover(); x != nil || testNode.result == FAIL {
-						testNode.fail()
-						testNode.AddDetail(RESULT, "AfterEach failed before testcase %s", testCase.Name)
-						c <- testNode
-						continue
-					}
-				}
-
-				c <- testNode
+		testCase := testCase
+		prerequisites := make([]*prerequisite, 0, len(testCase.DependsOn))
+		for _, name := range testCase.DependsOn {
+			if p, ok := tracked[byName[name]]; ok {
+				prerequisites = append(prerequisites, p)
+			}
+		}
+		// resolve plans a case that does not run.
+		resolve := func(testNode *Assertion) {
+			tracked[testCase] = newPrerequisite(testCase.Name, 1)
+			jobs = append(jobs, caseJob{
+				testCase: testCase,
+				name:     testCase.Name,
+				node:     testNode,
+				tracker:  tracked[testCase],
+				resolved: true,
+			})
+		}
+
+		if problem, ok := problems[testCase]; ok {
+			testNode := testCase.newNode(t, testCase.Name, node, logger)
+			testNode.AddDetail(RESULT, "Cannot run testcase %s: %s", testCase.Name, problem)
+			testNode.fail()
+			resolve(testNode)
+			continue
+		}
+
+		if testCase.Ignore || testCase.Case == nil {
+			testNode := testCase.newNode(t, testCase.Name, node, logger)
+			testNode.setResult(IGNORE)
+			resolve(testNode)
+			continue
+		}
+
+		var reason string
+		if x, stack := capture(func() { reason = testCase.skipReason() }); x != nil {
+			testNode := testCase.newNode(t, testCase.Name, node, logger)
+			recordPanic(testNode, "SkipIf", x, stack)
+			testNode.AddDetail(RESULT, "SkipIf failed for testcase %s", testCase.Name)
+			resolve(testNode)
+			continue
+		}
+		if reason != "" {
+			testNode := testCase.newNode(t, testCase.Name, node, logger)
+			testNode.AddDetail(SKIP, "Skipped, because %s", reason)
+			testNode.setResult(SKIPPED)
+			resolve(testNode)
+			continue
+		}
+
+		if testCase.Parameterize != nil {
+			var parameters [][]interface{}
+			if x, stack := capture(func() { parameters = testCase.Parameterize() }); x != nil {
+				testNode := testCase.newNode(t, testCase.Name, node, logger)
+				recordPanic(testNode, "Parameterize", x, stack)
+				testNode.AddDetail(RESULT, "Parameterize failed for testcase %s", testCase.Name)
+				resolve(testNode)
+				continue
+			}
+			tracked[testCase] = newPrerequisite(testCase.Name, len(parameters))
+			for i, param := range parameters {
+				caseName := fmt.Sprintf("%s[%d]", testCase.Name, i)
+				jobs = append(jobs, caseJob{
+					testCase:      testCase,
+					name:          caseName,
+					node:          testCase.newNode(t, caseName, node, logger),
+					params:        param,
+					timeout:       testCase.timeout(t, config),
+					prerequisites: prerequisites,
+					tracker:       tracked[testCase],
+				})
 			}
 		} else {
-			testNode := NewAssertion(testCase.Name, TEST_CASE, node, logger)
-			testNode.result = IGNORE
-			c <- testNode
+			tracked[testCase] = newPrerequisite(testCase.Name, 1)
+			jobs = append(jobs, caseJob{
+				testCase:      testCase,
+				name:          testCase.Name,
+				node:          testCase.newNode(t, testCase.Name, node, logger),
+				timeout:       testCase.timeout(t, config),
+				prerequisites: prerequisites,
+				tracker:       tracked[testCase],
+			})
 		}
 	}
+	return jobs
+}
 
-	if t.AfterAll != nil {
-		logger.Log(STEP, "Running AfterAll")
-		t.AfterAll(node)
+// runJob runs a single execution once its prerequisites have finished,
+// attempting it again as long as the retry policy of its case allows, and
+// hands the settled node to the listener. It skips the execution when a
+// prerequisite did not pass.
+func (t *TestFeature) runJob(job caseJob, c chan *Assertion) {
+	reason := job.awaitPrerequisites()
+	testNode := job.node
+	testNode.begin(EVENT_CASE_START)
+	defer func() {
+		testNode.conclude()
+		testNode.settle()
+		testNode.finish(EVENT_CASE_END)
+		job.tracker.record(testNode.Result())
+		c <- testNode
+	}()
+	if reason != "" {
+		testNode.AddDetail(SKIP, "Skipped, because %s", reason)
+		testNode.setResult(SKIPPED)
+		return
 	}
-	if x := recover(); x != nil {
-		node.fail()
-		logger.Log(RESULT, "AfterAll failed on feature %s", t.Name)
+
+	retry := job.testCase.Retry
+	maxAttempts := retry.maxAttempts()
+	delay := retry.delay()
+	for attempt := 1; ; attempt++ {
+		testNode.setAttempts(attempt)
+		if maxAttempts > 1 {
+			testNode.AddDetail(ATTEMPT, "Attempt %d/%d of testcase %s", attempt, maxAttempts, job.name)
+		}
+		crashed := t.runAttempt(job)
+		if !testNode.Result().Failed() {
+			if attempt > 1 {
+				testNode.AddDetail(RESULT, "testcase %s passed on retry, attempt %d/%d", job.name, attempt, maxAttempts)
+			}
+			return
+		}
+		if attempt >= maxAttempts || !retry.retries(crashed) {
+			return
+		}
+		testNode.AddDetail(ATTEMPT, "Attempt %d/%d of testcase %s failed, retrying in %v", attempt, maxAttempts, job.name, delay)
+		testNode = testNode.renew()
+		job.node = testNode
+		time.Sleep(delay)
+		delay = retry.next(delay)
 	}
-	defer logger.Log(FEATURE_END, "End running feature %s", t.Name)
+}
+
+// runAttempt wraps the case in BeforeEach and AfterEach. AfterEach, then
+// the cleanups of the case's fixtures, run even when BeforeEach or the case
+// itself failed or panicked. It reports whether any of them panicked or
+// timed out.
+func (t *TestFeature) runAttempt(job caseJob) (crashed bool) {
+	testNode := job.node
+	ready := true
+	if t.BeforeEach != nil {
+		testNode.AddDetail(STEP, "Running BeforeEach before testcase %s", job.name)
+		if callHook(testNode, "BeforeEach", job.timeout, func() { t.BeforeEach(testNode) }) {
+			crashed = true
+		}
+		if testNode.Result().Failed() {
+			testNode.AddDetail(RESULT, "BeforeEach failed before testcase %s", job.name)
+			ready = false
+		}
+		if testNode.Result() == SKIPPED {
+			testNode.AddDetail(RESULT, "BeforeEach skipped testcase %s", job.name)
+			ready = false
+		}
+	}
+
+	if ready && job.testCase.runCase(job.name, testNode, job.timeout, job.params...) {
+		crashed = true
+	}
+
+	if t.AfterEach != nil {
+		testNode.AddDetail(STEP, "Running AfterEach after testcase %s", job.name)
+		if callHook(testNode, "AfterEach", job.timeout, func() { t.AfterEach(testNode) }) {
+			testNode.AddDetail(RESULT, "AfterEach failed after testcase %s", job.name)
+			crashed = true
+		}
+	}
+	if testNode.runCleanups() {
+		crashed = true
+	}
+	return crashed
+}
+
+func (t *TestFeature) timeout(config runConfig) time.Duration {
+	if t.Timeout > 0 {
+		return t.Timeout
+	}
+	return config.timeout
+}
+
+func (t *TestCase) timeout(feature *TestFeature, config runConfig) time.Duration {
+	if t.Timeout > 0 {
+		return t.Timeout
+	}
+	return feature.timeout(config)
+}
 
+func (t *TestCase) newNode(feature *TestFeature, name string, parent *Assertion, logger Logger) *Assertion {
+	node := NewAssertion(name, TEST_CASE, parent, logger)
+	node.setTags(t.tags(feature))
+	return node
+}
+
+// tags returns the tags of the feature followed by those of the case,
+// without duplicates.
+func (t *TestCase) tags(feature *TestFeature) []string {
+	tags := make([]string, 0, len(feature.Tags)+len(t.Tag))
+	seen := make(map[string]bool)
+	for _, tag := range append(append([]string{}, feature.Tags...), t.Tag...) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
 }
 
-func (t *TestCase) runCase(name string, testNode *Assertion, params ...interface{}) {
+func (t *TestCase) runCase(name string, testNode *Assertion, timeout time.Duration, params ...interface{}) (crashed bool) {
 	testNode.AddDetail(CASE_START, "Start running case %s", name)
-	t.Case(testNode, params...)
+	if call(testNode, "Case", timeout, func() { t.Case(testNode, params...) }) {
+		testNode.AddDetail(RESULT, "testcase %s failed", name)
+		crashed = true
+	}
 	testNode.AddDetail(CASE_END, "End running case %s", name)
+	return crashed
+}
+
+// callHook runs a hook through call, framed by hook events, and records
+// its timing on node.
+func callHook(node *Assertion, hook string, timeout time.Duration, fn func()) bool {
+	timing := Timing{Name: hook, Start: time.Now()}
+	node.emit(Event{Type: EVENT_HOOK_START, Hook: hook})
+	before, faults := node.Result(), node.faultCount()
+	crashed := call(node, hook, timeout, fn)
+	timing.End = time.Now()
+	result := PASS
+	switch after := node.Result(); {
+	case crashed || (after == ERROR && before != ERROR):
+		result = ERROR
+	case node.faultCount() != faults:
+		result = FAIL
+	case after == SKIPPED && before != SKIPPED:
+		result = SKIPPED
+	}
+	timing.Result = result
+	node.addHookTiming(timing)
+	node.emit(Event{Type: EVENT_HOOK_END, Hook: hook, Result: result, Duration: timing.Duration()})
+	return crashed
+}
+
+// call runs fn under protect. With a positive timeout, fn runs on its own
+// goroutine with a deadline on a context derived from that of node's
+// parent, and call stops waiting for it once the deadline fires, failing
+// node with a timeout Detail. fn returning because it saw the deadline
+// counts as a timeout too. The cancelled context stays in place for fn,
+// which may still be running, until the next call on node replaces it. It
+// reports whether fn panicked or timed out.
+func call(node *Assertion, stage string, timeout time.Duration, fn func()) bool {
+	if timeout <= 0 {
+		node.swapContext(nil)
+		return protect(node, stage, fn)
+	}
+	ctx, cancel := context.WithTimeout(node.parentContext(), timeout)
+	defer cancel()
+	node.swapContext(ctx)
+
+	done := make(chan bool, 1)
+	go func() {
+		done <- protect(node, stage, fn)
+	}()
+	var failed, finished bool
+	select {
+	case failed = <-done:
+		finished = true
+	case <-ctx.Done():
+		select {
+		case failed = <-done:
+			finished = true
+		default:
+		}
+	}
+	if finished && ctx.Err() != context.DeadlineExceeded {
+		node.swapContext(nil)
+		return failed
+	}
+	node.AddDetail(TIMEOUT, "%s timed out after %v", stage, timeout)
+	node.crash()
+	return true
+}
+
+// protect calls fn and turns a panic into a crash of node, recording the
+// panic value and the stack trace as a Detail. It reports whether fn panicked.
+// A failed requirement or a Skip unwinds fn as well, but is no crash: its
+// result has been recorded already.
+func protect(node *Assertion, stage string, fn func()) bool {
+	if x, stack := capture(fn); x != nil {
+		if _, ok := x.(failNow); ok {
+			node.AddDetail(RESULT, "%s stopped after a failed requirement", stage)
+			return false
+		}
+		if _, ok := x.(skipNow); ok {
+			return false
+		}
+		recordPanic(node, stage, x, stack)
+		return true
+	}
+	return false
+}
+
+// capture calls fn and returns the recovered panic value, if any, together
+// with the stack trace of the panicking goroutine.
+func capture(fn func()) (x interface{}, stack []byte) {
+	defer func() {
+		if x = recover(); x != nil {
+			stack = debug.Stack()
+		}
+	}()
+	fn()
+	return nil, nil
+}
+
+func recordPanic(node *Assertion, stage string, x interface{}, stack []byte) {
+	node.AddDetail(PANIC, "%s panicked: %v\n%s", stage, x, stack)
+	node.crash()
 }
 
 type Logger interface {
@@ -264,10 +592,20 @@ type Result int
 const (
 	NOTRUN Result = iota
 	PASS
+	// FAIL is a case that failed an assert.
 	FAIL
 	IGNORE
+	// SKIPPED is a case that was selected but decided not to run.
+	SKIPPED
+	// ERROR is a case that panicked or timed out, in itself or in a hook.
+	ERROR
 )
 
+// Failed reports whether the result is FAIL or ERROR.
+func (r Result) Failed() bool {
+	return r == FAIL || r == ERROR
+}
+
 func (r Result) String() string {
 	switch r {
 	case NOTRUN:
@@ -278,6 +616,10 @@ func (r Result) String() string {
 		return "Fail"
 	case IGNORE:
 		return "Ignore"
+	case SKIPPED:
+		return "Skipped"
+	case ERROR:
+		return "Error"
 	default:
 		return "unkonwn"
 	}
@@ -0,0 +1,452 @@
+package engine
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// runFeature runs feature outside of a suite and returns its node.
+func runFeature(t *testing.T, feature *TestFeature, tags ...string) *Assertion {
+	t.Helper()
+	root := NewAssertion("root", TEST_SUITE, nil, nopLogger{})
+	c := make(chan *Assertion, 100)
+	feature.RunFeature(root, nopLogger{}, c, tags...)
+	close(c)
+	for range c {
+	}
+	children := root.Children()
+	if len(children) != 1 {
+		t.Fatalf("got %d feature nodes, want 1", len(children))
+	}
+	return children[0]
+}
+
+func hasDetail(assertion *Assertion, name string, contains string) bool {
+	for _, detail := range assertion.GetDetails() {
+		if detail.Name == name && strings.Contains(detail.Message, contains) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRunFeatureRecoversPanics(t *testing.T) {
+	afterEach, afterAll := 0, 0
+	feature := &TestFeature{
+		Name:      "feature",
+		AfterEach: func(assertion *Assertion) { afterEach++ },
+		AfterAll:  func(assertion *Assertion) { afterAll++ },
+		TestCases: []TestCase{
+			{
+				Name: "panics",
+				Case: func(assertion *Assertion, args ...interface{}) {
+					panic("boom")
+				},
+			},
+			{
+				Name:         "bad parameters",
+				Parameterize: func() [][]interface{} { panic("no rows") },
+				Case:         func(assertion *Assertion, args ...interface{}) {},
+			},
+			{
+				Name: "runs",
+				Case: func(assertion *Assertion, args ...interface{}) {},
+			},
+		},
+	}
+
+	node := runFeature(t, feature)
+	cases := node.Children()
+	if len(cases) != 3 {
+		t.Fatalf("got %d case nodes, want 3", len(cases))
+	}
+	if cases[0].Result() != ERROR || !hasDetail(cases[0], PANIC, "boom") {
+		t.Errorf("panicking case not recorded: %v %v", cases[0].Result(), cases[0].GetDetails())
+	}
+	if cases[1].Result() != ERROR || !hasDetail(cases[1], PANIC, "no rows") {
+		t.Errorf("panicking Parameterize not recorded: %v %v", cases[1].Result(), cases[1].GetDetails())
+	}
+	if cases[2].Result() != PASS {
+		t.Errorf("case after a panic did not pass: %v", cases[2].GetDetails())
+	}
+	if afterEach != 2 || afterAll != 1 {
+		t.Errorf("AfterEach ran %d times and AfterAll %d times, want 2 and 1", afterEach, afterAll)
+	}
+}
+
+func TestRunFeatureBeforeAllPanic(t *testing.T) {
+	ran, afterAll := false, false
+	feature := &TestFeature{
+		Name:      "feature",
+		BeforeAll: func(assertion *Assertion) { panic("no setup") },
+		AfterAll:  func(assertion *Assertion) { afterAll = true },
+		TestCases: []TestCase{
+			{Name: "case", Case: func(assertion *Assertion, args ...interface{}) { ran = true }},
+		},
+	}
+
+	node := runFeature(t, feature)
+	if ran || !afterAll {
+		t.Fatalf("case ran %v, AfterAll ran %v", ran, afterAll)
+	}
+	if node.Result() != ERROR || !hasDetail(node, PANIC, "no setup") {
+		t.Fatalf("BeforeAll panic not recorded: %v", node.GetDetails())
+	}
+	if cases := node.Children(); len(cases) != 1 || cases[0].Result() != ERROR {
+		t.Fatalf("case of a crashed feature not reported as errored")
+	}
+
+	afterAll = false
+	feature.BeforeAll = func(assertion *Assertion) { assertion.Require().True(false, "connected") }
+	node = runFeature(t, feature)
+	if ran || !afterAll {
+		t.Fatalf("after a failed BeforeAll, case ran %v, AfterAll ran %v", ran, afterAll)
+	}
+	if cases := node.Children(); node.Result() != FAIL || cases[0].Result() != FAIL || !hasDetail(cases[0], RESULT, "BeforeAll failed") {
+		t.Fatalf("failed BeforeAll recorded as %v: %v", node.Result(), cases[0].GetDetails())
+	}
+}
+
+func TestRunFeatureTimeout(t *testing.T) {
+	cancelled := make(chan bool, 1)
+	feature := &TestFeature{
+		Name:    "feature",
+		Timeout: 50 * time.Millisecond,
+		TestCases: []TestCase{
+			{
+				Name: "hangs",
+				Case: func(assertion *Assertion, args ...interface{}) {
+					<-assertion.Context().Done()
+					cancelled <- true
+					select {}
+				},
+			},
+			{
+				Name:    "own timeout",
+				Timeout: time.Second,
+				Case: func(assertion *Assertion, args ...interface{}) {
+					time.Sleep(100 * time.Millisecond)
+				},
+			},
+		},
+	}
+
+	node := runFeature(t, feature)
+	cases := node.Children()
+	if cases[0].Result() != ERROR || !hasDetail(cases[0], TIMEOUT, "Case timed out after 50ms") {
+		t.Errorf("hung case not timed out: %v", cases[0].GetDetails())
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("context of the hung case was not cancelled")
+	}
+	if cases[1].Result() != PASS {
+		t.Errorf("case with its own timeout did not pass: %v", cases[1].GetDetails())
+	}
+}
+
+func TestRunFeatureContextAfterTimeout(t *testing.T) {
+	late := make(chan error, 1)
+	feature := &TestFeature{
+		Name: "feature",
+		TestCases: []TestCase{
+			{
+				Name:    "overruns",
+				Timeout: 20 * time.Millisecond,
+				Case: func(assertion *Assertion, args ...interface{}) {
+					time.Sleep(50 * time.Millisecond)
+					late <- assertion.Context().Err()
+				},
+			},
+		},
+	}
+
+	runFeature(t, feature)
+	select {
+	case err := <-late:
+		if err == nil {
+			t.Error("context seen after the deadline was not cancelled")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed-out case did not finish")
+	}
+}
+
+func TestRunFeatureTimeoutSeenByCase(t *testing.T) {
+	feature := &TestFeature{
+		Name: "feature",
+		TestCases: []TestCase{
+			{
+				Name:    "waits for its deadline",
+				Timeout: 10 * time.Millisecond,
+				Case: func(assertion *Assertion, args ...interface{}) {
+					<-assertion.Context().Done()
+				},
+			},
+		},
+	}
+
+	for i := 0; i < 8; i++ {
+		testCase := runFeature(t, feature).Children()[0]
+		if testCase.Result() != ERROR || !hasDetail(testCase, TIMEOUT, "Case timed out after 10ms") {
+			t.Fatalf("case ended %v: %v", testCase.Result(), testCase.GetDetails())
+		}
+	}
+}
+
+func TestRunFeatureRetry(t *testing.T) {
+	flakyRuns, panicRuns := 0, 0
+	feature := &TestFeature{
+		Name: "feature",
+		TestCases: []TestCase{
+			{
+				Name:  "flaky",
+				Retry: &RetryPolicy{MaxAttempts: 3, Delay: time.Millisecond, Backoff: 2},
+				Case: func(assertion *Assertion, args ...interface{}) {
+					flakyRuns++
+					assertion.AssertEquals(2, flakyRuns, "second run passes")
+				},
+			},
+			{
+				Name:  "panic only",
+				Retry: &RetryPolicy{MaxAttempts: 3, On: RETRY_ON_PANIC},
+				Case: func(assertion *Assertion, args ...interface{}) {
+					panicRuns++
+					assertion.AssertFail("not retried")
+				},
+			},
+		},
+	}
+
+	node := runFeature(t, feature)
+	cases := node.Children()
+	if flakyRuns != 2 || cases[0].Result() != PASS || !cases[0].Flaky() || cases[0].Attempts() != 2 {
+		t.Errorf("flaky case: runs %d, result %v, attempts %d", flakyRuns, cases[0].Result(), cases[0].Attempts())
+	}
+	if !hasDetail(cases[0], RESULT, "passed on retry") {
+		t.Errorf("retry not recorded: %v", cases[0].GetDetails())
+	}
+	if panicRuns != 1 || cases[1].Result() != FAIL || cases[1].Flaky() {
+		t.Errorf("assert failure retried on panic-only policy: runs %d", panicRuns)
+	}
+}
+
+func TestRunFeatureRetryAfterTimeout(t *testing.T) {
+	var attempts int32
+	stale := make(chan error, 1)
+	feature := &TestFeature{
+		Name: "feature",
+		TestCases: []TestCase{
+			{
+				Name:    "slow first",
+				Timeout: 20 * time.Millisecond,
+				Retry:   &RetryPolicy{MaxAttempts: 2, On: RETRY_ON_PANIC},
+				Case: func(assertion *Assertion, args ...interface{}) {
+					if atomic.AddInt32(&attempts, 1) > 1 {
+						return
+					}
+					time.Sleep(60 * time.Millisecond)
+					assertion.AssertFail("stale attempt")
+					stale <- assertion.Context().Err()
+				},
+			},
+		},
+	}
+
+	node := runFeature(t, feature)
+	select {
+	case err := <-stale:
+		if err == nil {
+			t.Error("stale attempt saw the context of the retry")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed-out attempt did not finish")
+	}
+	testNode := node.Children()[0]
+	if testNode.Result() != PASS || node.Result() != PASS || testNode.Attempts() != 2 {
+		t.Errorf("retried case ended %v in a %v feature", testNode.Result(), node.Result())
+	}
+	if hasDetail(testNode, ASSERT, "stale attempt") || !hasDetail(testNode, TIMEOUT, "Case timed out") {
+		t.Errorf("unexpected details %v", testNode.GetDetails())
+	}
+	if len(node.Children()) != 1 {
+		t.Errorf("feature has %d cases", len(node.Children()))
+	}
+}
+
+func TestRunFeatureRequire(t *testing.T) {
+	reached, afterEach := false, false
+	feature := &TestFeature{
+		Name:      "feature",
+		AfterEach: func(assertion *Assertion) { afterEach = true },
+		TestCases: []TestCase{
+			{
+				Name: "stops",
+				Case: func(assertion *Assertion, args ...interface{}) {
+					assertion.Require().NotNil(nil, "response")
+					reached = true
+				},
+			},
+		},
+	}
+
+	node := runFeature(t, feature)
+	testNode := node.Children()[0]
+	if reached || !afterEach {
+		t.Fatalf("case continued %v, AfterEach ran %v", reached, afterEach)
+	}
+	if testNode.Result() != FAIL || hasDetail(testNode, PANIC, "") || !hasDetail(testNode, RESULT, "failed requirement") {
+		t.Fatalf("failed requirement recorded as %v: %v", testNode.Result(), testNode.GetDetails())
+	}
+}
+
+func TestRunFeatureSkip(t *testing.T) {
+	t.Setenv("SPARKLE_TEST_DB", "")
+	reached, afterEach := false, 0
+	feature := &TestFeature{
+		Name: "feature",
+		BeforeEach: func(assertion *Assertion) {
+			assertion.SkipIf(assertion.Name() == "before each", "no fixture")
+		},
+		AfterEach: func(assertion *Assertion) { afterEach++ },
+		TestCases: []TestCase{
+			{
+				Name: "skips",
+				Case: func(assertion *Assertion, args ...interface{}) {
+					assertion.Skip("service too old")
+					reached = true
+				},
+			},
+			{
+				Name: "before each",
+				Case: func(assertion *Assertion, args ...interface{}) { reached = true },
+			},
+			{
+				Name:       "needs database",
+				RequireEnv: []string{"SPARKLE_TEST_DB"},
+				Case:       func(assertion *Assertion, args ...interface{}) { reached = true },
+			},
+			{
+				Name:   "condition",
+				SkipIf: func() (bool, string) { return true, "not on this platform" },
+				Case:   func(assertion *Assertion, args ...interface{}) { reached = true },
+			},
+			{
+				Name: "fails first",
+				Case: func(assertion *Assertion, args ...interface{}) {
+					assertion.AssertFail("expected")
+					assertion.Skip("too late")
+				},
+			},
+		},
+	}
+
+	node := runFeature(t, feature)
+	cases := make(map[string]*Assertion)
+	for _, testCase := range node.Children() {
+		cases[testCase.Name()] = testCase
+	}
+	if reached || afterEach != 3 {
+		t.Fatalf("skipped case continued %v, AfterEach ran %d times", reached, afterEach)
+	}
+	for name, reason := range map[string]string{
+		"skips":          "service too old",
+		"before each":    "no fixture",
+		"needs database": "SPARKLE_TEST_DB",
+		"condition":      "not on this platform",
+	} {
+		if testCase := cases[name]; testCase.Result() != SKIPPED || !hasDetail(testCase, SKIP, reason) {
+			t.Errorf("case %s ended %v: %v", name, testCase.Result(), testCase.GetDetails())
+		}
+	}
+	if result := cases["fails first"].Result(); result != FAIL {
+		t.Errorf("failed case skipped afterwards ended %v", result)
+	}
+	if node.Result() != FAIL {
+		t.Errorf("feature ended %v", node.Result())
+	}
+
+	feature = &TestFeature{
+		Name:      "feature",
+		BeforeAll: func(assertion *Assertion) { assertion.Skip("no database") },
+		TestCases: []TestCase{
+			{Name: "case", Case: func(assertion *Assertion, args ...interface{}) { reached = true }},
+		},
+	}
+	node = runFeature(t, feature)
+	if reached || node.Result() != SKIPPED || node.Children()[0].Result() != SKIPPED {
+		t.Errorf("BeforeAll skip ran the case %v, feature ended %v", reached, node.Result())
+	}
+
+	feature.AfterAll = func(assertion *Assertion) { assertion.AssertFail("teardown") }
+	node = runFeature(t, feature)
+	if node.Result() != FAIL || node.Parent().Result() != FAIL {
+		t.Errorf("AfterAll failure after a skip ended feature %v and suite %v", node.Result(), node.Parent().Result())
+	}
+}
+
+func TestRunFeatureTiming(t *testing.T) {
+	feature := &TestFeature{
+		Name:       "feature",
+		BeforeAll:  func(assertion *Assertion) { time.Sleep(10 * time.Millisecond) },
+		BeforeEach: func(assertion *Assertion) { time.Sleep(10 * time.Millisecond) },
+		TestCases: []TestCase{
+			{
+				Name: "slow",
+				Case: func(assertion *Assertion, args ...interface{}) {
+					time.Sleep(30 * time.Millisecond)
+				},
+			},
+			{Name: "ignore", Ignore: true},
+		},
+	}
+	node := runFeature(t, feature)
+	cases := node.Children()
+
+	slow := cases[0]
+	if slow.Duration() < 40*time.Millisecond {
+		t.Errorf("case took %v, want at least 40ms with its BeforeEach", slow.Duration())
+	}
+	if !slow.EndTime().Equal(slow.StartTime().Add(slow.Duration())) {
+		t.Errorf("case duration %v does not match its start and end", slow.Duration())
+	}
+	hooks := slow.HookTimings()
+	if len(hooks) != 1 || hooks[0].Name != "BeforeEach" || hooks[0].Duration() < 10*time.Millisecond {
+		t.Errorf("unexpected case hook timings %+v", hooks)
+	}
+	if hooks := node.HookTimings(); len(hooks) != 1 || hooks[0].Name != "BeforeAll" {
+		t.Errorf("unexpected feature hook timings %+v", hooks)
+	}
+	if node.StartTime().After(slow.StartTime()) || node.EndTime().Before(slow.EndTime()) {
+		t.Error("feature does not span its case")
+	}
+	if ignored := cases[1]; ignored.StartTime().IsZero() || ignored.Duration() != 0 {
+		t.Errorf("ignored case has duration %v", ignored.Duration())
+	}
+}
+
+func TestRunFeatureHookResults(t *testing.T) {
+	feature := &TestFeature{
+		Name:      "feature",
+		AfterEach: func(assertion *Assertion) {},
+		AfterAll:  func(assertion *Assertion) { assertion.AssertFail("teardown") },
+		TestCases: []TestCase{
+			{
+				Name: "fail",
+				Case: func(assertion *Assertion, args ...interface{}) {
+					assertion.AssertFail("expected")
+				},
+			},
+		},
+	}
+	node := runFeature(t, feature)
+	if hooks := node.Children()[0].HookTimings(); len(hooks) != 1 || hooks[0].Result != PASS {
+		t.Errorf("AfterEach of a failed case recorded as %+v", hooks)
+	}
+	if hooks := node.HookTimings(); len(hooks) != 1 || hooks[0].Name != "AfterAll" || hooks[0].Result != FAIL {
+		t.Errorf("AfterAll failing on a failed feature recorded as %+v", hooks)
+	}
+}
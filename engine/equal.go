@@ -0,0 +1,470 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EqualOption relaxes the deep equality used by AssertEquals and
+// AssertNotEquals.
+type EqualOption func(config *equalConfig)
+
+type equalConfig struct {
+	ignore    map[string]bool
+	unordered bool
+	tolerance float64
+}
+
+// IgnoreFields skips struct fields and string map keys with one of the given
+// names at any depth. A name may also be a full path as shown in the
+// failure Detail, e.g. "Data.Items[0].ID".
+func IgnoreFields(names ...string) EqualOption {
+	return func(config *equalConfig) {
+		for _, name := range names {
+			config.ignore[name] = true
+		}
+	}
+}
+
+// IgnoreOrder compares slices and arrays as multisets.
+func IgnoreOrder() EqualOption {
+	return func(config *equalConfig) {
+		config.unordered = true
+	}
+}
+
+// FloatTolerance treats floats as equal when they differ by at most delta.
+func FloatTolerance(delta float64) EqualOption {
+	return func(config *equalConfig) {
+		config.tolerance = math.Abs(delta)
+	}
+}
+
+func newEqualConfig(options []EqualOption) *equalConfig {
+	config := &equalConfig{ignore: make(map[string]bool)}
+	for _, option := range options {
+		option(config)
+	}
+	return config
+}
+
+func (c *equalConfig) ignored(path string, name string) bool {
+	return c.ignore[name] || c.ignore[path]
+}
+
+// equality walks two values side by side and collects every difference
+// together with the path it was found at.
+type equality struct {
+	config  *equalConfig
+	diffs   []string
+	visited map[[2]uintptr]bool
+}
+
+// deepEqual compares expected and actual and returns their differences, an
+// empty result meaning they are equal.
+func deepEqual(expected interface{}, actual interface{}, config *equalConfig) []string {
+	e := &equality{config: config, visited: make(map[[2]uintptr]bool)}
+	e.compare("", reflect.ValueOf(expected), reflect.ValueOf(actual))
+	return e.diffs
+}
+
+func (e *equality) report(path string, format string, args ...interface{}) {
+	if path == "" {
+		path = "(root)"
+	}
+	e.diffs = append(e.diffs, path+": "+fmt.Sprintf(format, args...))
+}
+
+// equal compares without recording, as needed to match unordered elements.
+func (e *equality) equal(x reflect.Value, y reflect.Value) bool {
+	probe := &equality{config: e.config, visited: e.visited}
+	probe.compare("", x, y)
+	return len(probe.diffs) == 0
+}
+
+func (e *equality) compare(path string, x reflect.Value, y reflect.Value) {
+	for x.IsValid() && x.Kind() == reflect.Interface && !x.IsNil() {
+		x = x.Elem()
+	}
+	for y.IsValid() && y.Kind() == reflect.Interface && !y.IsNil() {
+		y = y.Elem()
+	}
+	xNil, yNil := !x.IsValid() || isNilValue(x), !y.IsValid() || isNilValue(y)
+	if xNil || yNil {
+		// a nil interface differs from a typed nil, as it does for ==
+		if xNil != yNil || x.IsValid() != y.IsValid() || (x.IsValid() && x.Type() != y.Type()) {
+			e.report(path, "expected %s, but actual was %s", describe(x), describe(y))
+		}
+		return
+	}
+	if x.Type() != y.Type() {
+		e.report(path, "expected %s, but actual was %s", describe(x), describe(y))
+		return
+	}
+
+	if x.Type() == numberType {
+		if !equalNumbers(x.String(), y.String(), e.config.tolerance) {
+			e.report(path, "expected %s, but actual was %s", describe(x), describe(y))
+		}
+		return
+	}
+
+	switch x.Kind() {
+	case reflect.Ptr:
+		if x.Pointer() == y.Pointer() {
+			return
+		}
+		// only pairs still being compared count as visited, a probe that
+		// found a pair different must not make it equal for later ones
+		key := [2]uintptr{x.Pointer(), y.Pointer()}
+		if e.visited[key] {
+			return
+		}
+		e.visited[key] = true
+		defer delete(e.visited, key)
+		e.compare(path, x.Elem(), y.Elem())
+	case reflect.Struct:
+		if x.Type() == reflect.TypeOf(time.Time{}) && x.CanInterface() && y.CanInterface() {
+			if !x.Interface().(time.Time).Equal(y.Interface().(time.Time)) {
+				e.report(path, "expected %s, but actual was %s", describe(x), describe(y))
+			}
+			return
+		}
+		for i := 0; i < x.NumField(); i++ {
+			name := x.Type().Field(i).Name
+			fieldPath := joinPath(path, name)
+			if e.config.ignored(fieldPath, name) {
+				continue
+			}
+			e.compare(fieldPath, x.Field(i), y.Field(i))
+		}
+	case reflect.Slice, reflect.Array:
+		if e.config.unordered {
+			e.compareUnordered(path, x, y)
+			return
+		}
+		for i := 0; i < x.Len() && i < y.Len(); i++ {
+			e.compare(fmt.Sprintf("%s[%d]", path, i), x.Index(i), y.Index(i))
+		}
+		for i := y.Len(); i < x.Len(); i++ {
+			e.report(fmt.Sprintf("%s[%d]", path, i), "expected %s, but it was missing", describe(x.Index(i)))
+		}
+		for i := x.Len(); i < y.Len(); i++ {
+			e.report(fmt.Sprintf("%s[%d]", path, i), "unexpected %s", describe(y.Index(i)))
+		}
+	case reflect.Map:
+		for _, key := range sortedKeys(x) {
+			keyPath := fmt.Sprintf("%s[%s]", path, scalar(key))
+			if key.Kind() == reflect.String && e.config.ignored(keyPath, key.String()) {
+				continue
+			}
+			value := y.MapIndex(key)
+			if !value.IsValid() {
+				e.report(keyPath, "expected %s, but it was missing", describe(x.MapIndex(key)))
+				continue
+			}
+			e.compare(keyPath, x.MapIndex(key), value)
+		}
+		for _, key := range sortedKeys(y) {
+			keyPath := fmt.Sprintf("%s[%s]", path, scalar(key))
+			if key.Kind() == reflect.String && e.config.ignored(keyPath, key.String()) {
+				continue
+			}
+			if !x.MapIndex(key).IsValid() {
+				e.report(keyPath, "unexpected %s", describe(y.MapIndex(key)))
+			}
+		}
+	case reflect.Float32, reflect.Float64:
+		if diff := math.Abs(x.Float() - y.Float()); diff > e.config.tolerance || diff != diff {
+			e.report(path, "expected %s, but actual was %s", describe(x), describe(y))
+		}
+	case reflect.Func:
+		// non-nil functions are never equal
+		e.report(path, "cannot compare functions")
+	case reflect.Chan, reflect.UnsafePointer:
+		if x.Pointer() != y.Pointer() {
+			e.report(path, "expected %s, but actual was %s", describe(x), describe(y))
+		}
+	default:
+		if scalar(x) != scalar(y) {
+			e.report(path, "expected %s, but actual was %s", describe(x), describe(y))
+		}
+	}
+}
+
+func (e *equality) compareUnordered(path string, x reflect.Value, y reflect.Value) {
+	used := make([]bool, y.Len())
+	for i := 0; i < x.Len(); i++ {
+		found := false
+		for j := 0; j < y.Len(); j++ {
+			if !used[j] && e.equal(x.Index(i), y.Index(j)) {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			e.report(fmt.Sprintf("%s[%d]", path, i), "expected %s, but no such element was found", describe(x.Index(i)))
+		}
+	}
+	for j := 0; j < y.Len(); j++ {
+		if !used[j] {
+			e.report(fmt.Sprintf("%s[%d]", path, j), "unexpected %s", describe(y.Index(j)))
+		}
+	}
+}
+
+var numberType = reflect.TypeOf(json.Number(""))
+
+// equalNumbers compares decoded JSON numbers by value and exactly, so 1
+// equals 1.0 while integers beyond 2^53 stay apart, unless they are within
+// tolerance.
+func equalNumbers(x string, y string, tolerance float64) bool {
+	xRat, xOK := new(big.Rat).SetString(x)
+	yRat, yOK := new(big.Rat).SetString(y)
+	if !xOK || !yOK {
+		return x == y
+	}
+	if xRat.Cmp(yRat) == 0 {
+		return true
+	}
+	diff, _ := new(big.Rat).Sub(xRat, yRat).Float64()
+	return tolerance > 0 && math.Abs(diff) <= tolerance
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice, reflect.UnsafePointer:
+		return v.IsNil()
+	}
+	return false
+}
+
+// describe prints a value in the %T(%v) style of the other assert messages.
+func describe(v reflect.Value) string {
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "nil"
+	}
+	if isNilValue(v) {
+		return fmt.Sprintf("%s(nil)", v.Type())
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Ptr:
+		return v.Type().String()
+	}
+	return fmt.Sprintf("%s(%s)", v.Type(), scalar(v))
+}
+
+// scalar prints basic kinds without Interface, so unexported fields work.
+func scalar(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, 64)
+	case reflect.Complex64, reflect.Complex128:
+		return strconv.FormatComplex(v.Complex(), 'g', -1, 128)
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer, reflect.Ptr:
+		return fmt.Sprintf("0x%x", v.Pointer())
+	}
+	if v.CanInterface() {
+		return fmt.Sprintf("%v", v.Interface())
+	}
+	return v.String()
+}
+
+func sortedKeys(v reflect.Value) []reflect.Value {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return scalar(keys[i]) < scalar(keys[j])
+	})
+	return keys
+}
+
+// render prints a value over multiple lines, one field or element per line,
+// so that two renderings can be diffed line by line.
+func render(v reflect.Value, config *equalConfig) []string {
+	r := &renderer{config: config, visited: make(map[uintptr]bool)}
+	r.write("", "", v, "")
+	return r.lines
+}
+
+type renderer struct {
+	config  *equalConfig
+	lines   []string
+	visited map[uintptr]bool
+}
+
+func (r *renderer) write(indent string, prefix string, v reflect.Value, path string) {
+	for v.IsValid() && v.Kind() == reflect.Interface && !v.IsNil() {
+		v = v.Elem()
+	}
+	if !v.IsValid() || isNilValue(v) {
+		r.lines = append(r.lines, indent+prefix+"nil")
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if r.visited[v.Pointer()] {
+			r.lines = append(r.lines, indent+prefix+"<cycle>")
+			return
+		}
+		r.visited[v.Pointer()] = true
+		defer delete(r.visited, v.Pointer())
+		r.write(indent, prefix+"&", v.Elem(), path)
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) && v.CanInterface() {
+			r.lines = append(r.lines, indent+prefix+v.Interface().(time.Time).String())
+			return
+		}
+		r.lines = append(r.lines, indent+prefix+v.Type().String()+"{")
+		for i := 0; i < v.NumField(); i++ {
+			name := v.Type().Field(i).Name
+			fieldPath := joinPath(path, name)
+			if r.config.ignored(fieldPath, name) {
+				continue
+			}
+			r.write(indent+"  ", name+": ", v.Field(i), fieldPath)
+		}
+		r.lines = append(r.lines, indent+"}")
+	case reflect.Slice, reflect.Array:
+		r.lines = append(r.lines, indent+prefix+v.Type().String()+"{")
+		if r.config.unordered {
+			elements := make([][]string, 0, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				element := &renderer{config: r.config, visited: r.visited}
+				element.write(indent+"  ", "", v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+				elements = append(elements, element.lines)
+			}
+			sort.Slice(elements, func(i, j int) bool {
+				return strings.Join(elements[i], "\n") < strings.Join(elements[j], "\n")
+			})
+			for _, element := range elements {
+				r.lines = append(r.lines, element...)
+			}
+		} else {
+			for i := 0; i < v.Len(); i++ {
+				r.write(indent+"  ", "", v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+		r.lines = append(r.lines, indent+"}")
+	case reflect.Map:
+		r.lines = append(r.lines, indent+prefix+v.Type().String()+"{")
+		for _, key := range sortedKeys(v) {
+			keyPath := fmt.Sprintf("%s[%s]", path, scalar(key))
+			if key.Kind() == reflect.String && r.config.ignored(keyPath, key.String()) {
+				continue
+			}
+			r.write(indent+"  ", scalar(key)+": ", v.MapIndex(key), keyPath)
+		}
+		r.lines = append(r.lines, indent+"}")
+	default:
+		r.lines = append(r.lines, indent+prefix+scalar(v))
+	}
+}
+
+// diffContext is the number of unchanged lines kept around every change.
+const diffContext = 3
+
+// diffLines returns a line diff of expected and actual in unified style,
+// "-" marking expected lines and "+" actual ones.
+func diffLines(expected []string, actual []string) string {
+	n, m := len(expected), len(actual)
+	type edit struct {
+		op   byte
+		line string
+	}
+	edits := make([]edit, 0, n+m)
+	if n*m > 1<<22 {
+		for _, line := range expected {
+			edits = append(edits, edit{'-', line})
+		}
+		for _, line := range actual {
+			edits = append(edits, edit{'+', line})
+		}
+	} else {
+		// longest common subsequence, filled from the end
+		lcs := make([][]int, n+1)
+		for i := range lcs {
+			lcs[i] = make([]int, m+1)
+		}
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if expected[i] == actual[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && expected[i] == actual[j]:
+				edits = append(edits, edit{' ', expected[i]})
+				i++
+				j++
+			case j < m && (i == n || lcs[i][j+1] > lcs[i+1][j]):
+				edits = append(edits, edit{'+', actual[j]})
+				j++
+			default:
+				edits = append(edits, edit{'-', expected[i]})
+				i++
+			}
+		}
+	}
+
+	keep := make([]bool, len(edits))
+	for k, e := range edits {
+		if e.op == ' ' {
+			continue
+		}
+		for c := k - diffContext; c <= k+diffContext; c++ {
+			if c >= 0 && c < len(edits) {
+				keep[c] = true
+			}
+		}
+	}
+	var b strings.Builder
+	b.WriteString("--- expected\n+++ actual\n")
+	skipped := false
+	for k, e := range edits {
+		if !keep[k] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			b.WriteString("@@ ... @@\n")
+			skipped = false
+		}
+		b.WriteByte(e.op)
+		b.WriteByte(' ')
+		b.WriteString(e.line)
+		b.WriteByte('\n')
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,99 @@
+package engine
+
+import "time"
+
+type EventType string
+
+const (
+	EVENT_SUITE_START   EventType = "suite_start"
+	EVENT_SUITE_END     EventType = "suite_end"
+	EVENT_FEATURE_START EventType = "feature_start"
+	EVENT_FEATURE_END   EventType = "feature_end"
+	EVENT_HOOK_START    EventType = "hook_start"
+	EVENT_HOOK_END      EventType = "hook_end"
+	EVENT_CASE_START    EventType = "case_start"
+	EVENT_CASE_END      EventType = "case_end"
+	EVENT_DETAIL        EventType = "detail"
+)
+
+// Event is a single step of a run as it happens. Node is the suite, feature
+// or case the event is about; for hooks it is the feature of BeforeAll and
+// AfterAll, and the case of BeforeEach and AfterEach.
+type Event struct {
+	Type EventType
+	Time time.Time
+	Node *Assertion
+	// Hook names the hook of a hook event.
+	Hook string
+	// Result is set on end events. A hook ends with ERROR when it panicked
+	// or timed out, with FAIL when it failed an assert and with SKIPPED
+	// when it called Skip.
+	Result Result
+	// Duration is set on end events.
+	Duration time.Duration
+	// Detail is set on detail events.
+	Detail *Detail
+}
+
+// EventHandler is implemented by handlers that follow a run live rather
+// than receive finished cases. Events are delivered from the goroutines
+// running the features and cases, so OnEvent must be safe for concurrent
+// use.
+type EventHandler interface {
+	OnEvent(event Event)
+}
+
+// SetEventHandler makes every node of the tree rooted at a deliver its
+// events to handler. TestSuite sets it on its root for the handlers that
+// implement EventHandler; set it yourself when running features directly.
+func (a *Assertion) SetEventHandler(handler EventHandler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.events = handler
+}
+
+func (a *Assertion) eventHandler() EventHandler {
+	for node := a; node != nil; node = node.parent {
+		node.mu.Lock()
+		handler := node.events
+		node.mu.Unlock()
+		if handler != nil {
+			return handler
+		}
+	}
+	return nil
+}
+
+// emit delivers an event about a, filling in its time and node.
+func (a *Assertion) emit(event Event) {
+	handler := a.eventHandler()
+	if handler == nil {
+		return
+	}
+	event.Time = time.Now()
+	event.Node = a
+	handler.OnEvent(event)
+}
+
+// begin records the start time of a and delivers a start event.
+func (a *Assertion) begin(eventType EventType) {
+	a.mu.Lock()
+	a.start = time.Now()
+	a.mu.Unlock()
+	a.emit(Event{Type: eventType})
+}
+
+// finish records the end time of a, seals it against further writes and
+// delivers an end event carrying its result. A node that never began, such
+// as an ignored case, starts and ends at once.
+func (a *Assertion) finish(eventType EventType) {
+	a.mu.Lock()
+	a.end = time.Now()
+	if a.start.IsZero() {
+		a.start = a.end
+	}
+	a.sealed = true
+	duration := a.end.Sub(a.start)
+	a.mu.Unlock()
+	a.emit(Event{Type: eventType, Result: a.Result(), Duration: duration})
+}
@@ -0,0 +1,236 @@
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Filter selects the cases to run by their tags and names. A nil *Filter
+// selects every case.
+type Filter struct {
+	tags tagExpression
+	name *regexp.Regexp
+}
+
+// NewFilter compiles a tag expression and a name pattern, either of which
+// may be empty to select everything.
+//
+// A tag expression combines tags with && (and), || (or), ! (not) and
+// parentheses, as "(api || grpc) && !flaky". A comma is an || with the
+// lowest precedence, so "smoke,api" selects cases with either tag. Cases
+// carry the tags of their feature as well as their own.
+//
+// The name pattern is a regular expression matched against "feature/case".
+func NewFilter(tagExpression string, namePattern string) (*Filter, error) {
+	f := &Filter{}
+	if strings.TrimSpace(tagExpression) != "" {
+		expr, err := parseTagExpression(tagExpression)
+		if err != nil {
+			return nil, err
+		}
+		f.tags = expr
+	}
+	if namePattern != "" {
+		re, err := regexp.Compile(namePattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name pattern %q: %w", namePattern, err)
+		}
+		f.name = re
+	}
+	return f, nil
+}
+
+// tagFilter compiles the tags of RunFeature and TestSuite.Run, each a tag
+// expression, selecting cases matching any of them.
+func tagFilter(tags []string) (*Filter, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return NewFilter(strings.Join(tags, ","), "")
+}
+
+// Match reports whether the filter selects a case of feature.
+func (f *Filter) Match(feature *TestFeature, testCase *TestCase) bool {
+	if f == nil {
+		return true
+	}
+	if f.tags != nil && !f.tags.match(testCase.tags(feature)) {
+		return false
+	}
+	if f.name != nil && !f.name.MatchString(feature.Name+"/"+testCase.Name) {
+		return false
+	}
+	return true
+}
+
+// matchAll reports whether every filter selects a case of feature.
+func matchAll(filters []*Filter, feature *TestFeature, testCase *TestCase) bool {
+	for _, f := range filters {
+		if !f.Match(feature, testCase) {
+			return false
+		}
+	}
+	return true
+}
+
+type tagExpression interface {
+	match(tags []string) bool
+}
+
+type tagName string
+
+func (t tagName) match(tags []string) bool {
+	for _, tag := range tags {
+		if tag == string(t) {
+			return true
+		}
+	}
+	return false
+}
+
+type tagNot struct {
+	expr tagExpression
+}
+
+func (t tagNot) match(tags []string) bool {
+	return !t.expr.match(tags)
+}
+
+type tagAnd []tagExpression
+
+func (t tagAnd) match(tags []string) bool {
+	for _, expr := range t {
+		if !expr.match(tags) {
+			return false
+		}
+	}
+	return true
+}
+
+type tagOr []tagExpression
+
+func (t tagOr) match(tags []string) bool {
+	for _, expr := range t {
+		if expr.match(tags) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTagExpression parses
+//
+//	list := or (',' or)*
+//	or   := and ('||' and)*
+//	and  := not ('&&' not)*
+//	not  := '!' not | '(' list ')' | tag
+func parseTagExpression(input string) (tagExpression, error) {
+	p := &tagParser{input: input}
+	expr, err := p.list()
+	if err == nil && p.next() != "" {
+		err = fmt.Errorf("unexpected %q at %d", p.next(), p.pos)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag expression %q: %w", input, err)
+	}
+	return expr, nil
+}
+
+type tagParser struct {
+	input string
+	pos   int
+}
+
+// next returns the token at the current position without consuming it.
+func (p *tagParser) next() string {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	rest := p.input[p.pos:]
+	switch {
+	case rest == "":
+		return ""
+	case strings.HasPrefix(rest, "&&"), strings.HasPrefix(rest, "||"):
+		return rest[:2]
+	case strings.ContainsRune("!(),", rune(rest[0])):
+		return rest[:1]
+	}
+	end := strings.IndexFunc(rest, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune("&|!(),", r)
+	})
+	if end < 0 {
+		return rest
+	}
+	if end == 0 {
+		return rest[:1]
+	}
+	return rest[:end]
+}
+
+func (p *tagParser) consume(token string) {
+	p.pos += len(token)
+}
+
+func (p *tagParser) list() (tagExpression, error) {
+	return p.binary(",", p.or, func(exprs []tagExpression) tagExpression { return tagOr(exprs) })
+}
+
+func (p *tagParser) or() (tagExpression, error) {
+	return p.binary("||", p.and, func(exprs []tagExpression) tagExpression { return tagOr(exprs) })
+}
+
+func (p *tagParser) and() (tagExpression, error) {
+	return p.binary("&&", p.not, func(exprs []tagExpression) tagExpression { return tagAnd(exprs) })
+}
+
+func (p *tagParser) binary(operator string, operand func() (tagExpression, error), combine func([]tagExpression) tagExpression) (tagExpression, error) {
+	expr, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	exprs := []tagExpression{expr}
+	for p.next() == operator {
+		p.consume(operator)
+		expr, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return combine(exprs), nil
+}
+
+func (p *tagParser) not() (tagExpression, error) {
+	token := p.next()
+	switch token {
+	case "!":
+		p.consume(token)
+		expr, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return tagNot{expr: expr}, nil
+	case "(":
+		p.consume(token)
+		expr, err := p.list()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing ')' at %d", p.pos)
+		}
+		p.consume(")")
+		return expr, nil
+	case "":
+		return nil, fmt.Errorf("missing tag at %d", p.pos)
+	case "&&", "||", ")", ",", "&", "|":
+		return nil, fmt.Errorf("unexpected %q at %d", token, p.pos)
+	}
+	p.consume(token)
+	return tagName(token), nil
+}
@@ -0,0 +1,134 @@
+package engine
+
+import "testing"
+
+func TestTagExpression(t *testing.T) {
+	tests := []struct {
+		expr  string
+		tags  []string
+		match bool
+	}{
+		{"smoke", []string{"smoke"}, true},
+		{"smoke", []string{"api"}, false},
+		{"smoke && !slow", []string{"smoke"}, true},
+		{"smoke && !slow", []string{"smoke", "slow"}, false},
+		{"(api || grpc) && !flaky", []string{"grpc"}, true},
+		{"(api || grpc) && !flaky", []string{"api", "flaky"}, false},
+		{"(api || grpc) && !flaky", []string{"smoke"}, false},
+		{"a || b && c", []string{"a"}, true},
+		{"a || b && c", []string{"b"}, false},
+		{"!!a", []string{"a"}, true},
+		{"smoke,api", []string{"api"}, true},
+		{"smoke, api && slow", []string{"api"}, false},
+		{"v1.2-beta", []string{"v1.2-beta"}, true},
+	}
+	for _, test := range tests {
+		expr, err := parseTagExpression(test.expr)
+		if err != nil {
+			t.Errorf("%q: %v", test.expr, err)
+			continue
+		}
+		if match := expr.match(test.tags); match != test.match {
+			t.Errorf("%q on %v matched %v, want %v", test.expr, test.tags, match, test.match)
+		}
+	}
+
+	for _, invalid := range []string{"&& a", "a &&", "(a", "a)", "a & b", "a | b", "!", "a b", "()"} {
+		if _, err := parseTagExpression(invalid); err == nil {
+			t.Errorf("%q parsed without error", invalid)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	feature := &TestFeature{
+		Name: "orders",
+		Tags: []string{"api"},
+		TestCases: []TestCase{
+			{Name: "create", Tag: []string{"smoke"}, Case: func(assertion *Assertion, args ...interface{}) {}},
+			{Name: "cancel", Tag: []string{"slow", "api"}, Case: func(assertion *Assertion, args ...interface{}) {}},
+			{Name: "refund", Case: func(assertion *Assertion, args ...interface{}) {}},
+		},
+	}
+	names := func(node *Assertion) []string {
+		list := make([]string, 0)
+		for _, child := range node.Children() {
+			list = append(list, child.Name())
+		}
+		return list
+	}
+
+	node := runFeature(t, feature, "api && !slow")
+	if got := names(node); len(got) != 2 || got[0] != "create" || got[1] != "refund" {
+		t.Fatalf("ran %v, want [create refund]", got)
+	}
+	if tags := node.Children()[0].Tags(); len(tags) != 2 || tags[0] != "api" || tags[1] != "smoke" {
+		t.Fatalf("case tags %v, want inherited [api smoke]", tags)
+	}
+	if tags := node.Children()[1].Tags(); len(tags) != 1 || tags[0] != "api" {
+		t.Fatalf("case tags %v, want inherited [api]", tags)
+	}
+
+	node = runFeature(t, feature, "smoke", "slow")
+	if got := names(node); len(got) != 2 || got[0] != "create" || got[1] != "cancel" {
+		t.Fatalf("ran %v, want [create cancel]", got)
+	}
+
+	node = runFeature(t, feature, "smoke &&")
+	if node.Result() != FAIL || len(node.Children()) != 0 || !hasDetail(node, RESULT, "invalid tag expression") {
+		t.Fatal("an invalid tag expression did not fail the feature")
+	}
+
+	filter, err := NewFilter("", "^orders/(create|refund)$")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := NewAssertion("root", TEST_SUITE, nil, nopLogger{})
+	c := make(chan *Assertion, 10)
+	feature.RunTestCase([]*TestCase{&feature.TestCases[1], &feature.TestCases[2]}, root, c, nopLogger{}, filter)
+	if got := names(root.Children()[0]); len(got) != 1 || got[0] != "refund" {
+		t.Fatalf("ran %v, want [refund]", got)
+	}
+
+	root = NewAssertion("root", TEST_SUITE, nil, nopLogger{})
+	feature.RunFeature(root, nopLogger{}, c, "grpc")
+	if len(root.Children()) != 0 {
+		t.Fatal("a feature without selected cases got a node")
+	}
+
+	if _, err := NewFilter("", "("); err == nil {
+		t.Fatal("an invalid name pattern compiled")
+	}
+}
+
+func TestSuiteFilter(t *testing.T) {
+	filter, err := NewFilter("", "create")
+	if err != nil {
+		t.Fatal(err)
+	}
+	suite := &TestSuite{
+		Name: "suite",
+		Features: []*TestFeature{
+			{
+				Name: "orders",
+				Tags: []string{"api"},
+				TestCases: []TestCase{
+					{Name: "create", Case: func(assertion *Assertion, args ...interface{}) {}},
+					{Name: "create slowly", Tag: []string{"slow"}, Case: func(assertion *Assertion, args ...interface{}) {}},
+					{Name: "cancel", Case: func(assertion *Assertion, args ...interface{}) {}},
+				},
+			},
+			{
+				Name:      "users",
+				TestCases: []TestCase{{Name: "create", Case: func(assertion *Assertion, args ...interface{}) {}}},
+			},
+		},
+		Filter: filter,
+	}
+	if summary := suite.Run("api && !slow"); summary.Total != 1 || summary.Pass != 1 || len(summary.Root.Children()) != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if summary := suite.Run("api &&"); len(summary.Errors) != 1 || summary.Total != 0 || summary.Root.Result() != FAIL {
+		t.Fatalf("unexpected summary for an invalid expression %+v", summary)
+	}
+}
@@ -0,0 +1,92 @@
+package engine
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Fixtures holds the resources hooks provide to cases, such as a token
+// obtained in BeforeAll or a database driver. Every node of the Assertion
+// tree has its own store, and a lookup that misses falls back to the store
+// of its parent: a case sees what its feature and its suite hold, while
+// what a case or its BeforeEach sets stays private to it. Fixtures is safe
+// for concurrent use.
+type Fixtures struct {
+	owner  *Assertion
+	mu     sync.RWMutex
+	values map[string]interface{}
+}
+
+// Fixtures returns the store of the Assertion. The Assertion of BeforeAll
+// and AfterAll is the feature's, that of BeforeEach, the case and AfterEach
+// is the case's.
+func (a *Assertion) Fixtures() *Fixtures {
+	return a.fixtures
+}
+
+// Set stores value under key in this scope, shadowing any value the
+// enclosing scopes hold under the same key.
+func (f *Fixtures) Set(key string, value interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.values == nil {
+		f.values = make(map[string]interface{})
+	}
+	f.values[key] = value
+}
+
+// Provide stores value under key like Set, and registers cleanup to run
+// when the scope ends, see Assertion.Cleanup.
+func (f *Fixtures) Provide(key string, value interface{}, cleanup func()) {
+	f.Set(key, value)
+	if cleanup != nil {
+		f.owner.Cleanup(cleanup)
+	}
+}
+
+// Value returns the value stored under key in this scope or the nearest
+// enclosing one.
+func (f *Fixtures) Value(key string) (interface{}, bool) {
+	for s := f; s != nil; s = s.parent() {
+		s.mu.RLock()
+		value, ok := s.values[key]
+		s.mu.RUnlock()
+		if ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// Get looks key up like Value and stores the value into target, a non-nil
+// pointer to a type the value is assignable to, as in
+//
+//	var token string
+//	if assertion.Fixtures().Get("token", &token) { ... }
+//
+// It reports false, leaving target untouched, when no scope holds key or
+// the value is nil or has another type. It panics when target is not a
+// pointer.
+func (f *Fixtures) Get(key string, target interface{}) bool {
+	pointer := reflect.ValueOf(target)
+	if pointer.Kind() != reflect.Ptr || pointer.IsNil() {
+		panic("engine: Fixtures.Get target must be a non-nil pointer")
+	}
+	value, ok := f.Value(key)
+	if !ok {
+		return false
+	}
+	element := pointer.Elem()
+	if value == nil || !reflect.TypeOf(value).AssignableTo(element.Type()) {
+		return false
+	}
+	element.Set(reflect.ValueOf(value))
+	return true
+}
+
+func (f *Fixtures) parent() *Fixtures {
+	if f.owner.parent == nil {
+		return nil
+	}
+	return f.owner.parent.fixtures
+}
@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFixtures(t *testing.T) {
+	var mu sync.Mutex
+	var cleaned []string
+	cleanup := func(name string) func() {
+		return func() {
+			mu.Lock()
+			cleaned = append(cleaned, name)
+			mu.Unlock()
+		}
+	}
+	suite := &TestSuite{
+		Name:     "suite",
+		Parallel: 2,
+		BeforeAll: func(assertion *Assertion) {
+			assertion.Fixtures().Provide("db", "driver", cleanup("db"))
+			assertion.Fixtures().Set("env", "suite")
+		},
+		Features: []*TestFeature{
+			{
+				Name:     "feature",
+				Parallel: 4,
+				BeforeAll: func(assertion *Assertion) {
+					assertion.Fixtures().Provide("token", "secret", cleanup("token"))
+					assertion.Fixtures().Set("env", "feature")
+				},
+				BeforeEach: func(assertion *Assertion) {
+					assertion.Fixtures().Provide("session", assertion.Name(), cleanup("session "+assertion.Name()))
+				},
+				TestCases: []TestCase{
+					{
+						Name: "reads",
+						Case: func(assertion *Assertion, args ...interface{}) {
+							var db, token, env, session string
+							fixtures := assertion.Fixtures()
+							assertion.AssertTrue(fixtures.Get("db", &db) && db == "driver", "suite fixture")
+							assertion.AssertTrue(fixtures.Get("token", &token) && token == "secret", "feature fixture")
+							assertion.AssertTrue(fixtures.Get("env", &env) && env == "feature", "shadowed fixture")
+							assertion.AssertTrue(fixtures.Get("session", &session) && session == "reads", "case fixture")
+							var wrong int
+							assertion.AssertFalse(fixtures.Get("token", &wrong), "mistyped fixture")
+							fixtures.Set("private", true)
+						},
+					},
+					{
+						Name: "isolated",
+						Case: func(assertion *Assertion, args ...interface{}) {
+							_, ok := assertion.Fixtures().Value("private")
+							assertion.AssertFalse(ok, "fixture of another case")
+						},
+					},
+					{
+						Name: "cleans up after a panic",
+						Case: func(assertion *Assertion, args ...interface{}) {
+							assertion.Fixtures().Provide("first", 1, cleanup("first"))
+							assertion.Fixtures().Provide("broken", 2, func() { panic("close failed") })
+							assertion.Fixtures().Provide("last", 3, cleanup("last"))
+							panic("boom")
+						},
+					},
+				},
+			},
+		},
+	}
+
+	summary := suite.Run()
+	cases := make(map[string]*Assertion)
+	for _, testCase := range summary.Root.Children()[0].Children() {
+		cases[testCase.Name()] = testCase
+	}
+	for _, name := range []string{"reads", "isolated"} {
+		if testCase := cases[name]; testCase.Result() != PASS {
+			t.Errorf("case %s ended %v: %v", name, testCase.Result(), testCase.GetDetails())
+		}
+	}
+	broken := cases["cleans up after a panic"]
+	if broken.Result() != ERROR || !hasDetail(broken, PANIC, "Cleanup panicked: close failed") {
+		t.Errorf("panicking cleanup recorded as %v: %v", broken.Result(), broken.GetDetails())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	got := strings.Join(cleaned, ",")
+	if !strings.Contains(got, "last,first,session cleans up after a panic") {
+		t.Errorf("case cleanups ran as %s", got)
+	}
+	if !strings.HasSuffix(got, ",token,db") || len(cleaned) != 7 {
+		t.Errorf("cleanups ran as %s", got)
+	}
+}
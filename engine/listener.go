@@ -4,6 +4,15 @@ type MessageHandler interface {
 	Send(assertion *Assertion)
 }
 
+// FinishHandler is implemented by handlers that need to know when a run is
+// over, typically to write a report. TestSuite calls OnFinish with the root
+// of the Assertion tree once every case has been delivered.
+type FinishHandler interface {
+	OnFinish(root *Assertion) error
+}
+
+// StartListener delivers every assertion sent on c to handler until quit
+// fires. To deliver to several handlers pass a Multiplexer.
 func StartListener(handler MessageHandler, c chan *Assertion, quit chan bool) {
 	// fmt.Println("start listener")
 	for {
@@ -13,7 +22,15 @@ func StartListener(handler MessageHandler, c chan *Assertion, quit chan bool) {
 			handler.Send(assertion)
 		case <-quit:
 			// fmt.Println("end listener")
-			return
+			// drain what was sent before quit, select picks at random
+			for {
+				select {
+				case assertion := <-c:
+					handler.Send(assertion)
+				default:
+					return
+				}
+			}
 		}
 	}
 
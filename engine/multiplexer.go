@@ -0,0 +1,205 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+)
+
+// StartHandler is implemented by handlers that need to prepare before the
+// first case is delivered, such as opening a file. TestSuite calls OnStart
+// with the root of the Assertion tree before running any feature.
+type StartHandler interface {
+	OnStart(root *Assertion) error
+}
+
+// Multiplexer delivers every Assertion and Event to any number of handlers.
+// Each handler is fed from its own queue on its own goroutine, so a slow
+// handler does not hold back the run or the other handlers, and a handler
+// that panics is recovered and dropped without affecting the others.
+//
+// A Multiplexer is used once: Start it, pass it to StartListener, and
+// Finish it when the run is over. TestSuite does all three for its Handlers.
+type Multiplexer struct {
+	queues []*handlerQueue
+}
+
+func NewMultiplexer(handlers ...MessageHandler) *Multiplexer {
+	m := &Multiplexer{queues: make([]*handlerQueue, 0, len(handlers))}
+	for _, handler := range handlers {
+		if handler == nil {
+			continue
+		}
+		m.queues = append(m.queues, newHandlerQueue(handler))
+	}
+	return m
+}
+
+// Start calls OnStart on the handlers implementing StartHandler and starts
+// delivering. A handler whose OnStart fails or panics receives nothing.
+func (m *Multiplexer) Start(root *Assertion) []error {
+	errs := make([]error, 0)
+	for _, q := range m.queues {
+		if starter, ok := q.handler.(StartHandler); ok {
+			if err := q.guard("OnStart", func() error { return starter.OnStart(root) }); err != nil {
+				errs = append(errs, err)
+				q.drop()
+			}
+		}
+		go q.run()
+	}
+	return errs
+}
+
+// Send queues assertion for every handler.
+func (m *Multiplexer) Send(assertion *Assertion) {
+	for _, q := range m.queues {
+		q.push(queued{assertion: assertion})
+	}
+}
+
+// OnEvent queues event for every handler implementing EventHandler.
+func (m *Multiplexer) OnEvent(event Event) {
+	for _, q := range m.queues {
+		if _, ok := q.handler.(EventHandler); ok {
+			q.push(queued{event: &event})
+		}
+	}
+}
+
+// HasEventHandler reports whether any handler implements EventHandler.
+func (m *Multiplexer) HasEventHandler() bool {
+	for _, q := range m.queues {
+		if _, ok := q.handler.(EventHandler); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Finish waits until every handler has received everything queued for it,
+// then calls OnFinish on the handlers implementing FinishHandler and still
+// running. It returns the errors of OnFinish together with the panics
+// recovered from handlers.
+func (m *Multiplexer) Finish(root *Assertion) []error {
+	for _, q := range m.queues {
+		q.close()
+	}
+	errs := make([]error, 0)
+	for _, q := range m.queues {
+		<-q.done
+		if q.isDropped() {
+			if q.err != nil {
+				errs = append(errs, q.err)
+			}
+			continue
+		}
+		if finisher, ok := q.handler.(FinishHandler); ok {
+			if err := q.guard("OnFinish", func() error { return finisher.OnFinish(root) }); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
+}
+
+type queued struct {
+	assertion *Assertion
+	event     *Event
+}
+
+// handlerQueue is an unbounded queue in front of a single handler.
+type handlerQueue struct {
+	handler MessageHandler
+	mu      sync.Mutex
+	cond    *sync.Cond
+	items   []queued
+	closed  bool
+	dropped bool
+	done    chan struct{}
+	// err is the panic that dropped the handler, read once done is closed.
+	err error
+}
+
+func newHandlerQueue(handler MessageHandler) *handlerQueue {
+	q := &handlerQueue{handler: handler, done: make(chan struct{})}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *handlerQueue) push(item queued) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || q.dropped {
+		return
+	}
+	q.items = append(q.items, item)
+	q.cond.Signal()
+}
+
+func (q *handlerQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Signal()
+}
+
+func (q *handlerQueue) drop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dropped = true
+	q.items = nil
+}
+
+func (q *handlerQueue) isDropped() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+func (q *handlerQueue) run() {
+	defer close(q.done)
+	for {
+		q.mu.Lock()
+		for len(q.items) == 0 && !q.closed && !q.dropped {
+			q.cond.Wait()
+		}
+		if q.dropped || len(q.items) == 0 {
+			q.mu.Unlock()
+			return
+		}
+		item := q.items[0]
+		q.items[0] = queued{}
+		q.items = q.items[1:]
+		q.mu.Unlock()
+
+		if err := q.deliver(item); err != nil {
+			q.err = err
+			q.drop()
+			return
+		}
+	}
+}
+
+func (q *handlerQueue) deliver(item queued) error {
+	if item.event != nil {
+		return q.guard("OnEvent", func() error {
+			q.handler.(EventHandler).OnEvent(*item.event)
+			return nil
+		})
+	}
+	return q.guard("Send", func() error {
+		q.handler.Send(item.assertion)
+		return nil
+	})
+}
+
+// guard calls fn, turning a panic into an error naming the handler.
+func (q *handlerQueue) guard(method string, fn func() error) (err error) {
+	if x, _ := capture(func() { err = fn() }); x != nil {
+		return fmt.Errorf("handler %T panicked in %s: %v", q.handler, method, x)
+	}
+	if err != nil {
+		return fmt.Errorf("handler %T: %w", q.handler, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+type lifecycleHandler struct {
+	recordHandler
+	started  *Assertion
+	finished *Assertion
+	delay    time.Duration
+	panics   bool
+}
+
+func (h *lifecycleHandler) OnStart(root *Assertion) error {
+	h.started = root
+	return nil
+}
+
+func (h *lifecycleHandler) Send(assertion *Assertion) {
+	if h.panics {
+		panic("broken handler")
+	}
+	time.Sleep(h.delay)
+	h.recordHandler.Send(assertion)
+}
+
+func (h *lifecycleHandler) OnFinish(root *Assertion) error {
+	h.finished = root
+	return nil
+}
+
+func TestMultiplexer(t *testing.T) {
+	slow := &lifecycleHandler{delay: 30 * time.Millisecond}
+	fast := &lifecycleHandler{}
+	broken := &lifecycleHandler{panics: true}
+
+	var testCases []TestCase
+	for i := 0; i < 5; i++ {
+		testCases = append(testCases, TestCase{
+			Name: "case",
+			Case: func(assertion *Assertion, args ...interface{}) {},
+		})
+	}
+	suite := &TestSuite{
+		Name:     "suite",
+		Features: []*TestFeature{{Name: "feature", TestCases: testCases}},
+		Handlers: []MessageHandler{slow, broken, fast},
+	}
+	start := time.Now()
+	summary := suite.Run()
+
+	if len(summary.Errors) != 1 || !strings.Contains(summary.Errors[0].Error(), "panicked in Send: broken handler") {
+		t.Fatalf("unexpected errors: %v", summary.Errors)
+	}
+	for name, h := range map[string]*lifecycleHandler{"slow": slow, "fast": fast} {
+		if len(h.assertions) != 5 {
+			t.Errorf("%s handler received %d assertions, want 5", name, len(h.assertions))
+		}
+		if h.started != summary.Root || h.finished != summary.Root {
+			t.Errorf("%s handler was not started and finished with the root", name)
+		}
+	}
+	if broken.finished != nil {
+		t.Error("a handler that panicked was finished")
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("run returned after %v, before the slow handler drained", elapsed)
+	}
+}
+
+func TestMultiplexerSlowHandler(t *testing.T) {
+	block := make(chan struct{})
+	slow := &blockingHandler{block: block}
+	fast := &recordHandler{}
+	mux := NewMultiplexer(slow, fast)
+	mux.Start(nil)
+	for i := 0; i < 3; i++ {
+		mux.Send(NewAssertion("case", TEST_CASE, nil, nopLogger{}))
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		fast.mu.Lock()
+		received := len(fast.assertions)
+		fast.mu.Unlock()
+		if received == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("fast handler received %d assertions while the slow one was blocked", received)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(block)
+	if errs := mux.Finish(nil); len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if slow.received != 3 {
+		t.Fatalf("slow handler received %d assertions, want 3", slow.received)
+	}
+}
+
+type blockingHandler struct {
+	block    chan struct{}
+	received int
+}
+
+func (h *blockingHandler) Send(assertion *Assertion) {
+	<-h.block
+	h.received++
+}
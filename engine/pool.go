@@ -0,0 +1,34 @@
+package engine
+
+import "sync"
+
+// workerPool runs functions on at most size goroutines at a time.
+type workerPool struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+func newWorkerPool(size int) *workerPool {
+	if size < 1 {
+		size = 1
+	}
+	return &workerPool{sem: make(chan struct{}, size)}
+}
+
+// Go blocks until a worker is free and runs fn on it.
+func (p *workerPool) Go(fn func()) {
+	p.wg.Add(1)
+	p.sem <- struct{}{}
+	go func() {
+		defer func() {
+			<-p.sem
+			p.wg.Done()
+		}()
+		fn()
+	}()
+}
+
+// Wait blocks until every submitted function has returned.
+func (p *workerPool) Wait() {
+	p.wg.Wait()
+}
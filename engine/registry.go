@@ -0,0 +1,87 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+)
+
+// registry holds the features compiled into a binary, in registration order.
+type registry struct {
+	mu       sync.Mutex
+	features []*TestFeature
+	names    map[string]*TestFeature
+}
+
+func newRegistry() *registry {
+	return &registry{features: make([]*TestFeature, 0), names: make(map[string]*TestFeature)}
+}
+
+var defaultRegistry = newRegistry()
+
+// Register makes features known to runners such as the sparkle command
+// line. Call it from an init function of the package defining them, so that
+// importing the package is enough to run them. It panics when a feature is
+// nil or its name is already registered.
+func Register(features ...*TestFeature) {
+	defaultRegistry.register(features...)
+}
+
+// Registered returns every registered feature, in registration order.
+func Registered() []*TestFeature {
+	return defaultRegistry.registered()
+}
+
+// Lookup returns the registered feature called name, or nil.
+func Lookup(name string) *TestFeature {
+	return defaultRegistry.lookup(name)
+}
+
+// LookupTag returns the registered features tagged tag themselves or with
+// at least one case tagged tag, in registration order.
+func LookupTag(tag string) []*TestFeature {
+	return defaultRegistry.lookupTag(tag)
+}
+
+func (r *registry) register(features ...*TestFeature) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, feature := range features {
+		if feature == nil {
+			panic("engine: Register feature is nil")
+		}
+		if _, ok := r.names[feature.Name]; ok {
+			panic(fmt.Sprintf("engine: Register called twice for feature %q", feature.Name))
+		}
+		r.names[feature.Name] = feature
+		r.features = append(r.features, feature)
+	}
+}
+
+func (r *registry) registered() []*TestFeature {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	features := make([]*TestFeature, len(r.features))
+	copy(features, r.features)
+	return features
+}
+
+func (r *registry) lookup(name string) *TestFeature {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.names[name]
+}
+
+func (r *registry) lookupTag(tag string) []*TestFeature {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	features := make([]*TestFeature, 0)
+	for _, feature := range r.features {
+		for i := range feature.TestCases {
+			if tagName(tag).match(feature.TestCases[i].tags(feature)) {
+				features = append(features, feature)
+				break
+			}
+		}
+	}
+	return features
+}
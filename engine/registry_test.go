@@ -0,0 +1,34 @@
+package engine
+
+import "testing"
+
+func TestRegistry(t *testing.T) {
+	r := newRegistry()
+	login := &TestFeature{Name: "login", TestCases: []TestCase{{Name: "valid", Tag: []string{"smoke"}}}}
+	orders := &TestFeature{Name: "orders", TestCases: []TestCase{{Name: "create", Tag: []string{"slow"}}}}
+	r.register(login, orders)
+
+	if features := r.registered(); len(features) != 2 || features[0] != login || features[1] != orders {
+		t.Fatalf("unexpected registered features %v", features)
+	}
+	if r.lookup("orders") != orders || r.lookup("missing") != nil {
+		t.Fatal("unexpected lookup by name")
+	}
+	if features := r.lookupTag("smoke"); len(features) != 1 || features[0] != login {
+		t.Fatalf("unexpected lookup by tag %v", features)
+	}
+	if features := r.lookupTag("missing"); len(features) != 0 {
+		t.Fatalf("unexpected lookup by missing tag %v", features)
+	}
+
+	for name, feature := range map[string]*TestFeature{"duplicate": {Name: "login"}, "nil": nil} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering a %s feature did not panic", name)
+				}
+			}()
+			r.register(feature)
+		}()
+	}
+}
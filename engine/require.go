@@ -0,0 +1,116 @@
+package engine
+
+// Requirement offers the checks of an Assertion as hard assertions: the
+// failure is recorded like its Assert counterpart, then the running hook or
+// case stops immediately. The runner reports that as a normal failure.
+type Requirement struct {
+	assertion *Assertion
+}
+
+// failNow is the panic value that unwinds a hook or case after a failed
+// requirement. protect recognizes it and does not treat it as a crash.
+type failNow struct{}
+
+// Require returns the hard assertion variant of a.
+func (a *Assertion) Require() *Requirement {
+	return &Requirement{assertion: a}
+}
+
+func (r *Requirement) check(ok bool) {
+	if !ok {
+		panic(failNow{})
+	}
+}
+
+func (r *Requirement) Equals(expected interface{}, actual interface{}, title string, options ...EqualOption) {
+	r.check(r.assertion.AssertEquals(expected, actual, title, options...))
+}
+
+func (r *Requirement) NotEquals(expected interface{}, actual interface{}, title string, options ...EqualOption) {
+	r.check(r.assertion.AssertNotEquals(expected, actual, title, options...))
+}
+
+func (r *Requirement) True(value bool, title string) {
+	r.check(r.assertion.AssertTrue(value, title))
+}
+
+func (r *Requirement) False(value bool, title string) {
+	r.check(r.assertion.AssertFalse(value, title))
+}
+
+func (r *Requirement) Nil(value interface{}, title string) {
+	r.check(r.assertion.AssertNil(value, title))
+}
+
+func (r *Requirement) NotNil(value interface{}, title string) {
+	r.check(r.assertion.AssertNotNil(value, title))
+}
+
+func (r *Requirement) Contains(container interface{}, element interface{}, title string) {
+	r.check(r.assertion.AssertContains(container, element, title))
+}
+
+func (r *Requirement) Greater(actual interface{}, than interface{}, title string) {
+	r.check(r.assertion.AssertGreater(actual, than, title))
+}
+
+func (r *Requirement) Less(actual interface{}, than interface{}, title string) {
+	r.check(r.assertion.AssertLess(actual, than, title))
+}
+
+func (r *Requirement) InDelta(expected float64, actual float64, delta float64, title string) {
+	r.check(r.assertion.AssertInDelta(expected, actual, delta, title))
+}
+
+func (r *Requirement) MatchesRegex(pattern string, actual string, title string) {
+	r.check(r.assertion.AssertMatchesRegex(pattern, actual, title))
+}
+
+func (r *Requirement) ErrorIs(err error, target error, title string) {
+	r.check(r.assertion.AssertErrorIs(err, target, title))
+}
+
+func (r *Requirement) ErrorAs(err error, target interface{}, title string) {
+	r.check(r.assertion.AssertErrorAs(err, target, title))
+}
+
+func (r *Requirement) Len(object interface{}, length int, title string) {
+	r.check(r.assertion.AssertLen(object, length, title))
+}
+
+func (r *Requirement) Empty(object interface{}, title string) {
+	r.check(r.assertion.AssertEmpty(object, title))
+}
+
+func (r *Requirement) Panics(fn func(), title string) {
+	r.check(r.assertion.AssertPanics(fn, title))
+}
+
+func (r *Requirement) Fail(title string) {
+	r.assertion.AssertFail(title)
+	r.check(false)
+}
+
+func (r *Requirement) JSONPath(body string, path string, expected interface{}, title string) {
+	r.check(r.assertion.AssertJSONPath(body, path, expected, title))
+}
+
+func (r *Requirement) JSONPathType(body string, path string, jsonType string, title string) {
+	r.check(r.assertion.AssertJSONPathType(body, path, jsonType, title))
+}
+
+func (r *Requirement) JSONPathExists(body string, path string, title string) {
+	r.check(r.assertion.AssertJSONPathExists(body, path, title))
+}
+
+func (r *Requirement) JSONPathNotExists(body string, path string, title string) {
+	r.check(r.assertion.AssertJSONPathNotExists(body, path, title))
+}
+
+func (r *Requirement) JSONPathLen(body string, path string, length int, title string) {
+	r.check(r.assertion.AssertJSONPathLen(body, path, length, title))
+}
+
+func (r *Requirement) JSONSchema(schema string, body string, title string) {
+	r.check(r.assertion.AssertJSONSchema(schema, body, title))
+}
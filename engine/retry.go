@@ -0,0 +1,66 @@
+package engine
+
+import "time"
+
+// RetryOn selects which failed attempts of a case are retried.
+type RetryOn int
+
+const (
+	// RETRY_ON_FAILURE retries after any failure, including failed asserts.
+	RETRY_ON_FAILURE RetryOn = iota
+	// RETRY_ON_PANIC retries only after a panic or a timeout.
+	RETRY_ON_PANIC
+)
+
+func (r RetryOn) String() string {
+	switch r {
+	case RETRY_ON_FAILURE:
+		return "On Failure"
+	case RETRY_ON_PANIC:
+		return "On Panic"
+	default:
+		return "unknown"
+	}
+}
+
+// RetryPolicy describes how often and when a failing case is run again.
+// Every attempt is recorded as Details on the Assertion the case ends with.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// Delay is the pause before the second attempt.
+	Delay time.Duration
+	// Backoff multiplies the delay after every further attempt. Values up to
+	// one keep the delay constant.
+	Backoff float64
+	// On selects which failures are retried.
+	On RetryOn
+}
+
+func (r *RetryPolicy) maxAttempts() int {
+	if r == nil || r.MaxAttempts < 1 {
+		return 1
+	}
+	return r.MaxAttempts
+}
+
+func (r *RetryPolicy) delay() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return r.Delay
+}
+
+func (r *RetryPolicy) next(delay time.Duration) time.Duration {
+	if r.Backoff <= 1 {
+		return delay
+	}
+	return time.Duration(float64(delay) * r.Backoff)
+}
+
+func (r *RetryPolicy) retries(crashed bool) bool {
+	if r == nil {
+		return false
+	}
+	return r.On == RETRY_ON_FAILURE || crashed
+}
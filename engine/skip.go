@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// skipNow is the panic value that unwinds a hook or case after Skip.
+// protect recognizes it and does not treat it as a crash.
+type skipNow struct{}
+
+// Skip records reason as a Detail, marks the Assertion SKIPPED and stops the
+// running hook or case immediately. AfterEach still runs. Called from
+// BeforeEach it skips the case, called from BeforeAll it skips every case of
+// the feature. A case that failed already stays failed.
+func (a *Assertion) Skip(reason string) {
+	a.appendDetail(Detail{
+		Name:       SKIP,
+		Message:    fmt.Sprintf("Skipped, because %s", reason),
+		RecordTime: time.Now(),
+	})
+	a.skip()
+	panic(skipNow{})
+}
+
+// SkipIf calls Skip with reason when condition holds.
+func (a *Assertion) SkipIf(condition bool, reason string) {
+	if condition {
+		a.Skip(reason)
+	}
+}
+
+// skip marks a as skipped, unless it has a result already.
+func (a *Assertion) skip() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.sealed && a.result == NOTRUN {
+		a.result = SKIPPED
+	}
+}
+
+// skipReason checks the declarative conditions of a case before it is
+// planned: the environment variables it requires, then its SkipIf. It
+// returns why the case must be skipped, or an empty string to run it.
+func (t *TestCase) skipReason() string {
+	for _, name := range t.RequireEnv {
+		if os.Getenv(name) == "" {
+			return fmt.Sprintf("environment variable %s is not set", name)
+		}
+	}
+	if t.SkipIf != nil {
+		if skip, reason := t.SkipIf(); skip {
+			if reason == "" {
+				reason = "SkipIf held"
+			}
+			return reason
+		}
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	SUITE_START = "Suite Start"
+	SUITE_END   = "Suite End"
+)
+
+// TestSuite is the root of a test run. It owns the features to execute and
+// the handlers every finished case is delivered to.
+type TestSuite struct {
+	Name     string
+	Features []*TestFeature
+	Handlers []MessageHandler
+	Logger   Logger
+	// Parallel is the maximum number of features run at the same time. It
+	// is also the case concurrency of features that do not set their own.
+	Parallel int
+	// Timeout is the default timeout of every case, BeforeAll and AfterAll
+	// call whose feature or case does not set one.
+	Timeout time.Duration
+	// Filter selects the cases to run, together with the tags given to Run.
+	Filter *Filter
+	// BeforeAll runs before any feature, typically to provide fixtures all
+	// features share. When it fails, panics, times out or calls Skip, no
+	// feature runs: their cases end with the result of the suite.
+	BeforeAll func(assertion *Assertion)
+	// AfterAll runs once every feature has finished, followed by the
+	// cleanups of the suite's fixtures.
+	AfterAll func(assertion *Assertion)
+}
+
+// Summary is the aggregated outcome of a TestSuite run.
+type Summary struct {
+	Root     *Assertion
+	Total    int
+	Pass     int
+	Fail     int
+	Error    int
+	Skipped  int
+	Ignore   int
+	Duration time.Duration
+	// Errors holds what the handlers returned from OnStart and OnFinish, and
+	// the panics recovered from them.
+	Errors []error
+}
+
+// Success reports whether no case, hook or filter of the run failed or
+// crashed.
+func (s *Summary) Success() bool {
+	if s.Root != nil && s.Root.Result().Failed() {
+		return false
+	}
+	return s.Fail == 0 && s.Error == 0
+}
+
+// Run executes every feature of the suite under a single TEST_SUITE node,
+// forwards finished cases to the registered handlers and blocks until all
+// of them have been delivered. With tags, only the cases matching any of
+// these tag expressions run, see NewFilter; an invalid expression fails
+// the run without running anything.
+func (s *TestSuite) Run(tags ...string) *Summary {
+	logger := s.Logger
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	root := NewAssertion(s.Name, TEST_SUITE, nil, logger)
+	summary := &Summary{Root: root}
+	mux := NewMultiplexer(s.Handlers...)
+	for _, err := range mux.Start(root) {
+		logger.Log(RESULT, "Handler failed: %s", err.Error())
+		summary.Errors = append(summary.Errors, err)
+	}
+	if mux.HasEventHandler() {
+		root.SetEventHandler(mux)
+	}
+	c := make(chan *Assertion, 10)
+	quit := make(chan bool, 1)
+
+	logger.Log(SUITE_START, "Start running suite %s", s.Name)
+	root.begin(EVENT_SUITE_START)
+	filter, err := tagFilter(tags)
+	if err != nil {
+		logger.Log(RESULT, "Cannot run suite %s: %s", s.Name, err.Error())
+		root.AddDetail(RESULT, "Cannot run suite %s: %s", s.Name, err.Error())
+		root.fail()
+		summary.Errors = append(summary.Errors, err)
+	}
+	go func() {
+		defer func() { quit <- true }()
+		if err != nil {
+			return
+		}
+		defer s.afterAll(root, logger)
+		config := runConfig{filters: []*Filter{s.Filter, filter}, parallel: s.Parallel, timeout: s.Timeout}
+		if s.BeforeAll != nil {
+			logger.Log(STEP, "Running BeforeAll")
+			callHook(root, "BeforeAll", s.Timeout, func() { s.BeforeAll(root) })
+			switch result := root.Result(); {
+			case result.Failed():
+				config.abort = fmt.Sprintf("BeforeAll failed on suite %s", s.Name)
+			case result == SKIPPED:
+				config.abort = fmt.Sprintf("BeforeAll skipped suite %s", s.Name)
+			}
+			if config.abort != "" {
+				logger.Log(RESULT, "%s", config.abort)
+				root.AddDetail(RESULT, "%s", config.abort)
+			}
+		}
+		pool := newWorkerPool(s.Parallel)
+		for _, feature := range s.Features {
+			feature := feature
+			pool.Go(func() {
+				feature.run(root, logger, c, feature.cases(), config)
+			})
+		}
+		pool.Wait()
+	}()
+	StartListener(mux, c, quit)
+	root.conclude()
+	root.finish(EVENT_SUITE_END)
+	summary.Duration = root.Duration()
+	for _, err := range mux.Finish(root) {
+		logger.Log(RESULT, "Handler failed: %s", err.Error())
+		summary.Errors = append(summary.Errors, err)
+	}
+	logger.Log(SUITE_END, "End running suite %s", s.Name)
+
+	counts := root.Counts()
+	summary.Total = counts.Total
+	summary.Pass = counts.Pass
+	summary.Fail = counts.Fail
+	summary.Error = counts.Error
+	summary.Skipped = counts.Skipped
+	summary.Ignore = counts.Ignore
+	return summary
+}
+
+// afterAll runs AfterAll, then the cleanups of the suite's fixtures.
+func (s *TestSuite) afterAll(root *Assertion, logger Logger) {
+	if s.AfterAll != nil {
+		logger.Log(STEP, "Running AfterAll")
+		if callHook(root, "AfterAll", s.Timeout, func() { s.AfterAll(root) }) {
+			logger.Log(RESULT, "AfterAll failed on suite %s", s.Name)
+		}
+	}
+	root.runCleanups()
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(logType string, message string, args ...interface{}) {}
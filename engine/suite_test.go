@@ -0,0 +1,288 @@
+package engine
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordHandler struct {
+	mu         sync.Mutex
+	assertions []*Assertion
+}
+
+func (h *recordHandler) Send(assertion *Assertion) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.assertions = append(h.assertions, assertion)
+}
+
+func TestSuiteRun(t *testing.T) {
+	handler := &recordHandler{}
+	suite := &TestSuite{
+		Name: "suite",
+		Features: []*TestFeature{
+			{
+				Name: "feature",
+				TestCases: []TestCase{
+					{
+						Name: "pass",
+						Case: func(assertion *Assertion, args ...interface{}) {
+							assertion.AssertEquals(2, 1+1, "sum")
+						},
+					},
+					{
+						Name: "fail",
+						Case: func(assertion *Assertion, args ...interface{}) {
+							assertion.AssertFail("expected")
+						},
+					},
+					{Name: "ignore", Ignore: true},
+				},
+			},
+		},
+		Handlers: []MessageHandler{handler},
+	}
+
+	summary := suite.Run()
+	if summary.Total != 3 || summary.Pass != 1 || summary.Fail != 1 || summary.Ignore != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if summary.Success() {
+		t.Fatal("summary with a failed case reported success")
+	}
+	if len(handler.assertions) != 3 {
+		t.Fatalf("handler received %d assertions, want 3", len(handler.assertions))
+	}
+	if summary.Root.Result() != FAIL {
+		t.Fatalf("root result %v, want %v", summary.Root.Result(), FAIL)
+	}
+}
+
+func TestSuiteRunParallel(t *testing.T) {
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	enter := func() {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+	}
+	leave := func() {
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+
+	var testCases []TestCase
+	for i := 0; i < 8; i++ {
+		testCases = append(testCases, TestCase{
+			Name: "case",
+			Case: func(assertion *Assertion, args ...interface{}) {
+				enter()
+				defer leave()
+				time.Sleep(20 * time.Millisecond)
+				assertion.AssertFail("each case fails")
+			},
+		})
+	}
+	testCases = append(testCases, TestCase{
+		Name:   "serial",
+		Serial: true,
+		Case: func(assertion *Assertion, args ...interface{}) {
+			mu.Lock()
+			defer mu.Unlock()
+			if running != 0 {
+				assertion.AssertFail("serial case overlapped")
+			}
+		},
+	})
+
+	suite := &TestSuite{
+		Name: "suite",
+		Features: []*TestFeature{
+			{Name: "feature", Parallel: 4, TestCases: testCases},
+		},
+	}
+	summary := suite.Run()
+	if summary.Total != 9 || summary.Fail != 8 || summary.Pass != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if maxRunning < 2 || maxRunning > 4 {
+		t.Fatalf("max concurrent cases %d, want between 2 and 4", maxRunning)
+	}
+}
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *eventRecorder) Send(assertion *Assertion) {}
+
+func (r *eventRecorder) OnEvent(event Event) {
+	if event.Type == EVENT_DETAIL && event.Detail.Name != ASSERT {
+		return
+	}
+	record := string(event.Type) + " " + event.Node.Name()
+	if event.Detail != nil {
+		record += " " + event.Detail.Name
+	}
+	if event.Hook != "" {
+		record += " " + event.Hook
+	}
+	switch event.Type {
+	case EVENT_SUITE_END, EVENT_FEATURE_END, EVENT_HOOK_END, EVENT_CASE_END:
+		record += " " + event.Result.String()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, record)
+}
+
+func TestSuiteEvents(t *testing.T) {
+	recorder := &eventRecorder{}
+	suite := &TestSuite{
+		Name: "suite",
+		Features: []*TestFeature{
+			{
+				Name:       "feature",
+				BeforeAll:  func(assertion *Assertion) {},
+				BeforeEach: func(assertion *Assertion) {},
+				TestCases: []TestCase{
+					{
+						Name: "pass",
+						Case: func(assertion *Assertion, args ...interface{}) {},
+					},
+					{
+						Name: "fail",
+						Case: func(assertion *Assertion, args ...interface{}) {
+							assertion.AssertFail("expected")
+						},
+					},
+					{Name: "ignore", Ignore: true},
+				},
+			},
+		},
+		Handlers: []MessageHandler{recorder},
+	}
+	suite.Run()
+
+	expected := []string{
+		"suite_start suite",
+		"feature_start feature",
+		"hook_start feature BeforeAll",
+		"hook_end feature BeforeAll Pass",
+		"case_start pass",
+		"hook_start pass BeforeEach",
+		"hook_end pass BeforeEach Pass",
+		"case_end pass Pass",
+		"case_start fail",
+		"hook_start fail BeforeEach",
+		"hook_end fail BeforeEach Pass",
+		"detail fail Assert",
+		"case_end fail Fail",
+		"case_end ignore Ignore",
+		"feature_end feature Fail",
+		"suite_end suite Fail",
+	}
+	if len(recorder.events) != len(expected) {
+		t.Fatalf("unexpected events:\n%s", strings.Join(recorder.events, "\n"))
+	}
+	for i := range expected {
+		if recorder.events[i] != expected[i] {
+			t.Fatalf("event %d was %q, want %q", i, recorder.events[i], expected[i])
+		}
+	}
+}
+
+func TestSuiteResults(t *testing.T) {
+	pass := func(assertion *Assertion, args ...interface{}) {}
+	suite := &TestSuite{
+		Name: "suite",
+		Features: []*TestFeature{
+			{
+				Name: "mixed",
+				TestCases: []TestCase{
+					{Name: "pass", Case: pass},
+					{Name: "fail", Case: func(assertion *Assertion, args ...interface{}) { assertion.AssertFail("expected") }},
+					{Name: "crash", Case: func(assertion *Assertion, args ...interface{}) { panic("boom") }},
+					{Name: "ignore", Ignore: true},
+				},
+			},
+			{Name: "green", TestCases: []TestCase{{Name: "pass", Case: pass}}},
+			{Name: "ignored", TestCases: []TestCase{{Name: "ignore", Ignore: true}}},
+		},
+	}
+	summary := suite.Run()
+	if summary.Total != 6 || summary.Pass != 2 || summary.Fail != 1 || summary.Error != 1 || summary.Ignore != 2 || summary.Success() {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	features := summary.Root.Children()
+	expected := []Result{ERROR, PASS, IGNORE}
+	for i, feature := range features {
+		if feature.Result() != expected[i] {
+			t.Errorf("feature %s ended %v, want %v", feature.Name(), feature.Result(), expected[i])
+		}
+	}
+	results := []Result{PASS, FAIL, ERROR, IGNORE}
+	for i, testCase := range features[0].Children() {
+		if testCase.Result() != results[i] {
+			t.Errorf("case %s ended %v, want %v", testCase.Name(), testCase.Result(), results[i])
+		}
+	}
+	if counts := features[0].Counts(); counts != (Counts{Total: 4, Pass: 1, Fail: 1, Error: 1, Ignore: 1}) {
+		t.Errorf("unexpected feature counts %+v", counts)
+	}
+	if summary.Root.Result() != ERROR {
+		t.Errorf("suite ended %v, want %v", summary.Root.Result(), ERROR)
+	}
+
+	green := &TestSuite{Name: "green", Features: []*TestFeature{suite.Features[1]}}
+	if summary := green.Run(); summary.Root.Result() != PASS || !summary.Success() {
+		t.Errorf("green suite ended %v", summary.Root.Result())
+	}
+}
+
+func TestSuiteBeforeAll(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		beforeAll func(assertion *Assertion)
+		want      Result
+	}{
+		{"skip", func(assertion *Assertion) { assertion.Skip("no database") }, SKIPPED},
+		{"require", func(assertion *Assertion) { assertion.Require().True(false, "connected") }, FAIL},
+		{"panic", func(assertion *Assertion) { panic("no connection") }, ERROR},
+	} {
+		ran, afterAll := false, false
+		suite := &TestSuite{
+			Name:      "suite",
+			BeforeAll: tc.beforeAll,
+			AfterAll:  func(assertion *Assertion) { afterAll = true },
+			Features: []*TestFeature{
+				{
+					Name: "feature",
+					TestCases: []TestCase{
+						{Name: "case", Case: func(assertion *Assertion, args ...interface{}) { ran = true }},
+					},
+				},
+			},
+		}
+		summary := suite.Run()
+		if ran || !afterAll {
+			t.Errorf("%s: case ran %v, AfterAll ran %v", tc.name, ran, afterAll)
+		}
+		feature := summary.Root.Children()[0]
+		testCase := feature.Children()[0]
+		if summary.Root.Result() != tc.want || feature.Result() != tc.want || testCase.Result() != tc.want {
+			t.Errorf("%s: suite %v, feature %v, case %v", tc.name, summary.Root.Result(), feature.Result(), testCase.Result())
+		}
+		if summary.Success() != (tc.want == SKIPPED) || summary.Pass != 0 {
+			t.Errorf("%s: summary %+v", tc.name, summary)
+		}
+	}
+}
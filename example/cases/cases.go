@@ -8,6 +8,10 @@ import (
 	"github.com/jimmyseraph/sparkle/logger"
 )
 
+func init() {
+	engine.Register(TestDemo())
+}
+
 func TestDemo() *engine.TestFeature {
 	return &engine.TestFeature{
 		Name: "Test Feature Example",
@@ -25,15 +29,18 @@ func TestDemo() *engine.TestFeature {
 	}
 }
 
-func Run() {
-	logger := logger.NewZapLogger()
-	c := make(chan *engine.Assertion, 10)
-	quit := make(chan bool, 1)
-	handler := handler.NewZapHandler()
-	go func() {
-		TestDemo().RunFeature(nil, logger, c)
-		quit <- true
-	}()
-
-	engine.StartListener(handler, c, quit)
+// Run reports through the reporters named in SPARKLE_REPORTER, the console
+// by default.
+func Run() (*engine.Summary, error) {
+	handlers, err := handler.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	suite := &engine.TestSuite{
+		Name:     "Test Suite Example",
+		Features: []*engine.TestFeature{TestDemo()},
+		Handlers: handlers,
+		Logger:   logger.NewZapLogger(),
+	}
+	return suite.Run(), nil
 }
@@ -0,0 +1,311 @@
+package handler
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+var _ engine.MessageHandler = (*allureHandler)(nil)
+var _ engine.FinishHandler = (*allureHandler)(nil)
+
+// allureHandler collects finished cases and writes them as Allure 2 result
+// files: one result per case, one container per feature, and one attachment
+// per captured request or response.
+type allureHandler struct {
+	dir   string
+	mu    sync.Mutex
+	cases []*engine.Assertion
+}
+
+func NewAllureHandler(dir string) *allureHandler {
+	if dir == "" {
+		dir = "allure-results"
+	}
+	return &allureHandler{dir: dir, cases: make([]*engine.Assertion, 0)}
+}
+
+func (h *allureHandler) Send(assertion *engine.Assertion) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cases = append(h.cases, assertion)
+}
+
+func (h *allureHandler) OnFinish(root *engine.Assertion) error {
+	return h.Write()
+}
+
+// Write writes the results of every case received so far. Use it when
+// driving the handler through engine.StartListener instead of a TestSuite.
+func (h *allureHandler) Write() error {
+	h.mu.Lock()
+	cases := make([]*engine.Assertion, len(h.cases))
+	copy(cases, h.cases)
+	h.mu.Unlock()
+
+	if err := os.MkdirAll(h.dir, 0755); err != nil {
+		return err
+	}
+	for _, group := range groupByFeature(cases) {
+		container := allureContainer{
+			UUID:     newUUID(),
+			Name:     featureName(group.feature),
+			Children: make([]string, 0),
+			Befores:  make([]allureFixture, 0),
+			Afters:   make([]allureFixture, 0),
+		}
+		if group.feature != nil {
+			container.Start = millis(group.feature.StartTime())
+			container.Stop = millis(group.feature.EndTime())
+			for _, hook := range group.feature.HookTimings() {
+				fixture := allureFixture{Name: hook.Name, Status: allureStatus(hook.Result), Stage: "finished", Start: millis(hook.Start), Stop: millis(hook.End)}
+				if strings.HasPrefix(hook.Name, "Before") {
+					container.Befores = append(container.Befores, fixture)
+				} else {
+					container.Afters = append(container.Afters, fixture)
+				}
+			}
+		}
+		for _, testCase := range group.cases {
+			result, err := h.writeResult(group.feature, testCase)
+			if err != nil {
+				return err
+			}
+			container.Children = append(container.Children, result.UUID)
+		}
+		if err := h.writeJSON(container.UUID+"-container.json", container); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type allureResult struct {
+	UUID          string              `json:"uuid"`
+	HistoryID     string              `json:"historyId"`
+	TestCaseID    string              `json:"testCaseId"`
+	Name          string              `json:"name"`
+	FullName      string              `json:"fullName"`
+	Status        string              `json:"status"`
+	StatusDetails *allureStatusDetail `json:"statusDetails,omitempty"`
+	Stage         string              `json:"stage"`
+	Start         int64               `json:"start"`
+	Stop          int64               `json:"stop"`
+	Labels        []allureLabel       `json:"labels"`
+	Steps         []allureStep        `json:"steps"`
+	Attachments   []allureAttachment  `json:"attachments"`
+}
+
+type allureStatusDetail struct {
+	Message string `json:"message,omitempty"`
+	Trace   string `json:"trace,omitempty"`
+	Flaky   bool   `json:"flaky,omitempty"`
+}
+
+type allureLabel struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type allureStep struct {
+	Name          string              `json:"name"`
+	Status        string              `json:"status"`
+	StatusDetails *allureStatusDetail `json:"statusDetails,omitempty"`
+	Stage         string              `json:"stage"`
+	Start         int64               `json:"start"`
+	Stop          int64               `json:"stop"`
+	Attachments   []allureAttachment  `json:"attachments"`
+}
+
+type allureAttachment struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Type   string `json:"type"`
+}
+
+type allureContainer struct {
+	UUID     string          `json:"uuid"`
+	Name     string          `json:"name"`
+	Children []string        `json:"children"`
+	Befores  []allureFixture `json:"befores"`
+	Afters   []allureFixture `json:"afters"`
+	Start    int64           `json:"start"`
+	Stop     int64           `json:"stop"`
+}
+
+type allureFixture struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Stage  string `json:"stage"`
+	Start  int64  `json:"start"`
+	Stop   int64  `json:"stop"`
+}
+
+func (h *allureHandler) writeResult(feature *engine.Assertion, testCase *engine.Assertion) (*allureResult, error) {
+	fullName := featureName(feature) + "." + testCase.Name()
+	result := &allureResult{
+		UUID:        newUUID(),
+		HistoryID:   digest(fullName),
+		TestCaseID:  digest(fullName),
+		Name:        testCase.Name(),
+		FullName:    fullName,
+		Status:      allureStatus(testCase.Result()),
+		Stage:       "finished",
+		Start:       millis(testCase.StartTime()),
+		Stop:        millis(testCase.EndTime()),
+		Labels:      allureLabels(feature, testCase),
+		Steps:       make([]allureStep, 0),
+		Attachments: make([]allureAttachment, 0),
+	}
+	if details, _ := failures(testCase); len(details) > 0 {
+		messages := make([]string, 0, len(details))
+		for _, detail := range details {
+			messages = append(messages, detail.Message)
+		}
+		result.StatusDetails = &allureStatusDetail{
+			Message: strings.SplitN(details[0].Message, "\n", 2)[0],
+			Trace:   strings.Join(messages, "\n\n"),
+		}
+	}
+	if testCase.Result() == engine.SKIPPED {
+		result.StatusDetails = &allureStatusDetail{Message: skipReason(testCase)}
+	}
+	if testCase.Flaky() {
+		if result.StatusDetails == nil {
+			result.StatusDetails = &allureStatusDetail{}
+		}
+		result.StatusDetails.Flaky = true
+	}
+
+	for _, detail := range testCase.GetDetails() {
+		at := millis(detail.RecordTime)
+		step := allureStep{
+			Name:        detail.Name + ": " + strings.SplitN(detail.Message, "\n", 2)[0],
+			Status:      allureStepStatus(detail),
+			Stage:       "finished",
+			Start:       at,
+			Stop:        at,
+			Attachments: make([]allureAttachment, 0),
+		}
+		if isCapture(detail) {
+			step.Name = detail.Name
+			attachment, err := h.writeAttachment(detail)
+			if err != nil {
+				return nil, err
+			}
+			step.Attachments = append(step.Attachments, attachment)
+			result.Attachments = append(result.Attachments, attachment)
+		} else if step.Status != "passed" {
+			step.StatusDetails = &allureStatusDetail{Message: detail.Message}
+		}
+		result.Steps = append(result.Steps, step)
+	}
+	for _, hook := range testCase.HookTimings() {
+		result.Steps = append(result.Steps, allureStep{
+			Name:        hook.Name,
+			Status:      allureStatus(hook.Result),
+			Stage:       "finished",
+			Start:       millis(hook.Start),
+			Stop:        millis(hook.End),
+			Attachments: make([]allureAttachment, 0),
+		})
+	}
+	sort.SliceStable(result.Steps, func(i, j int) bool {
+		return result.Steps[i].Start < result.Steps[j].Start
+	})
+	return result, h.writeJSON(result.UUID+"-result.json", result)
+}
+
+func (h *allureHandler) writeAttachment(detail engine.Detail) (allureAttachment, error) {
+	attachment := allureAttachment{Name: detail.Name, Type: "text/plain", Source: newUUID() + "-attachment.txt"}
+	if strings.HasPrefix(detail.Name, "Grpc") {
+		attachment.Type = "application/json"
+		attachment.Source = strings.TrimSuffix(attachment.Source, ".txt") + ".json"
+	}
+	return attachment, os.WriteFile(filepath.Join(h.dir, attachment.Source), []byte(detail.Message), 0644)
+}
+
+func (h *allureHandler) writeJSON(name string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(h.dir, name), data, 0644)
+}
+
+func allureLabels(feature *engine.Assertion, testCase *engine.Assertion) []allureLabel {
+	labels := []allureLabel{
+		{Name: "suite", Value: featureName(feature)},
+		{Name: "feature", Value: featureName(feature)},
+		{Name: "framework", Value: "sparkle"},
+		{Name: "language", Value: "go"},
+	}
+	if feature != nil && feature.Parent() != nil {
+		labels = append(labels, allureLabel{Name: "parentSuite", Value: feature.Parent().Name()})
+	}
+	for _, tag := range testCase.Tags() {
+		labels = append(labels, allureLabel{Name: "tag", Value: tag})
+	}
+	return labels
+}
+
+// allureStatus maps the result of a case or a hook to Allure's statuses,
+// where a failed assert is "failed" and a panic or a timeout is "broken".
+func allureStatus(result engine.Result) string {
+	switch result {
+	case engine.FAIL:
+		return "failed"
+	case engine.ERROR:
+		return "broken"
+	case engine.IGNORE, engine.SKIPPED:
+		return "skipped"
+	default:
+		return "passed"
+	}
+}
+
+func allureStepStatus(detail engine.Detail) string {
+	switch detail.Name {
+	case engine.ASSERT:
+		return "failed"
+	case engine.PANIC, engine.TIMEOUT:
+		return "broken"
+	case engine.SKIP:
+		return "skipped"
+	default:
+		return "passed"
+	}
+}
+
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func digest(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+func TestAllureHandler(t *testing.T) {
+	dir := t.TempDir()
+	suite := reportSuite(NewAllureHandler(dir))
+	suite.Features[0].AfterAll = func(assertion *engine.Assertion) { panic("teardown") }
+	if summary := suite.Run(); len(summary.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", summary.Errors)
+	}
+
+	results := make(map[string]allureResult)
+	containers := make([]allureContainer, 0)
+	attachments := 0
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case strings.HasSuffix(entry.Name(), "-result.json"):
+			var result allureResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				t.Fatal(err)
+			}
+			results[result.Name] = result
+		case strings.HasSuffix(entry.Name(), "-container.json"):
+			var container allureContainer
+			if err := json.Unmarshal(data, &container); err != nil {
+				t.Fatal(err)
+			}
+			containers = append(containers, container)
+		case strings.Contains(entry.Name(), "-attachment."):
+			attachments++
+			if content := string(data); content != "GET /users?name=<b>&rate=100%25" && content != "HTTP/1.1 200 OK" {
+				t.Errorf("unexpected attachment: %s", data)
+			}
+		}
+	}
+
+	statuses := map[string]string{"pass": "passed", "fail": "failed", "panic": "broken", "ignore": "skipped"}
+	for name, status := range statuses {
+		if results[name].Status != status {
+			t.Errorf("%s: expected status %s, but was %s", name, status, results[name].Status)
+		}
+	}
+	if len(containers) != 1 || containers[0].Name != "feature" || len(containers[0].Children) != 4 {
+		t.Fatalf("unexpected containers: %+v", containers)
+	}
+	if afters := containers[0].Afters; len(afters) != 1 || afters[0].Name != "AfterAll" || afters[0].Status != "broken" {
+		t.Errorf("unexpected afters: %+v", afters)
+	}
+	if attachments != 2 || len(results["pass"].Attachments) != 2 {
+		t.Errorf("expected two attachments, found %d", attachments)
+	}
+	labels := make(map[string]string)
+	for _, label := range results["pass"].Labels {
+		labels[label.Name] = label.Value
+	}
+	if labels["suite"] != "feature" || labels["parentSuite"] != "suite" || labels["tag"] != "smoke" {
+		t.Errorf("unexpected labels: %+v", results["pass"].Labels)
+	}
+	if details := results["fail"].StatusDetails; details == nil || details.Message != "Fail, because expected" {
+		t.Errorf("unexpected status details: %+v", details)
+	}
+}
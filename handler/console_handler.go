@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+var _ engine.MessageHandler = (*consoleHandler)(nil)
+var _ engine.EventHandler = (*consoleHandler)(nil)
+var _ engine.FinishHandler = (*consoleHandler)(nil)
+var _ outputCloser = (*consoleHandler)(nil)
+
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorGray   = "\033[90m"
+	colorBold   = "\033[1m"
+)
+
+// consoleHandler prints a line per finished case and, once the suite
+// finishes, the failed cases, features and suite with their Details, the
+// slowest cases, the totals and the wall time.
+type consoleHandler struct {
+	mu       sync.Mutex
+	writer   io.Writer
+	closer   io.Closer
+	color    bool
+	started  time.Time
+	failed   []*engine.Assertion
+	cases    []*engine.Assertion
+	pass     int
+	fail     int
+	errored  int
+	skipped  int
+	ignore   int
+	finished bool
+}
+
+// NewConsoleHandler prints to writer, in color when it is a terminal and
+// the NO_COLOR environment variable is not set.
+func NewConsoleHandler(writer io.Writer) *consoleHandler {
+	if writer == nil {
+		writer = os.Stdout
+	}
+	return &consoleHandler{writer: writer, color: isTerminal(writer) && os.Getenv("NO_COLOR") == ""}
+}
+
+func (h *consoleHandler) OnEvent(event engine.Event) {
+	if event.Type != engine.EVENT_SUITE_START {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.started = event.Time
+}
+
+func (h *consoleHandler) Send(assertion *engine.Assertion) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.started.IsZero() {
+		h.started = time.Now()
+	}
+	h.cases = append(h.cases, assertion)
+	mark, color := "✓", colorGreen
+	switch assertion.Result() {
+	case engine.FAIL:
+		mark, color = "✗", colorRed
+		h.fail++
+		h.failed = append(h.failed, assertion)
+	case engine.ERROR:
+		mark, color = "!", colorRed
+		h.errored++
+		h.failed = append(h.failed, assertion)
+	case engine.SKIPPED:
+		mark, color = "-", colorYellow
+		h.skipped++
+	case engine.IGNORE:
+		mark, color = "-", colorYellow
+		h.ignore++
+	default:
+		h.pass++
+	}
+	line := fmt.Sprintf("%s %s", h.paint(color, mark), casePath(assertion))
+	if !skipped(assertion.Result()) {
+		line += " " + h.paint(colorGray, "("+formatDuration(assertion.Duration())+")")
+	} else if assertion.Result() == engine.SKIPPED {
+		line += " " + h.paint(colorGray, "("+skipReason(assertion)+")")
+	}
+	if assertion.Flaky() {
+		line += " " + h.paint(colorYellow, fmt.Sprintf("[flaky, %d attempts]", assertion.Attempts()))
+	}
+	fmt.Fprintln(h.writer, line)
+}
+
+func (h *consoleHandler) OnFinish(root *engine.Assertion) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	broken := brokenNodes(root)
+	if len(h.failed) > 0 || len(broken) > 0 {
+		fmt.Fprintf(h.writer, "\n%s\n", h.paint(colorBold+colorRed, "Failures:"))
+		for i, testCase := range h.failed {
+			h.printFailure(i+1, casePath(testCase), testCase)
+		}
+		for i, node := range broken {
+			h.printFailure(len(h.failed)+i+1, node.Name(), node)
+		}
+	}
+
+	if ran := slowest(h.cases, 5); len(ran) > 1 {
+		fmt.Fprintf(h.writer, "\n%s\n", h.paint(colorBold, "Slowest cases:"))
+		for _, testCase := range ran {
+			line := fmt.Sprintf("  %8s  %s", formatDuration(testCase.Duration()), casePath(testCase))
+			if hooks := hookSummary(testCase); hooks != "" {
+				line += " " + h.paint(colorGray, "("+hooks+")")
+			}
+			fmt.Fprintln(h.writer, line)
+		}
+	}
+
+	total := h.pass + h.fail + h.errored + h.skipped + h.ignore
+	var elapsed time.Duration
+	if !h.started.IsZero() {
+		elapsed = time.Since(h.started)
+	}
+	status := h.paint(colorBold+colorGreen, "PASSED")
+	if h.fail > 0 || h.errored > 0 || (root != nil && root.Result().Failed()) {
+		status = h.paint(colorBold+colorRed, "FAILED")
+	}
+	counts := []string{
+		h.paint(colorGreen, fmt.Sprintf("%d passed", h.pass)),
+		h.paint(colorRed, fmt.Sprintf("%d failed", h.fail)),
+	}
+	if h.errored > 0 {
+		counts = append(counts, h.paint(colorRed, fmt.Sprintf("%d errored", h.errored)))
+	}
+	if h.skipped > 0 {
+		counts = append(counts, h.paint(colorYellow, fmt.Sprintf("%d skipped", h.skipped)))
+	}
+	counts = append(counts, h.paint(colorYellow, fmt.Sprintf("%d ignored", h.ignore)))
+	fmt.Fprintf(h.writer, "\n%s %d cases: %s in %v\n", status, total,
+		strings.Join(counts, ", "), formatDuration(elapsed))
+	return h.closeOutput()
+}
+
+func (h *consoleHandler) closeOutput() error {
+	return closeOnce(&h.closer)
+}
+
+// printFailure prints the n-th entry of the failures with every Detail of
+// node, failure Details in red.
+func (h *consoleHandler) printFailure(n int, name string, node *engine.Assertion) {
+	fmt.Fprintf(h.writer, "\n%d) %s\n", n, h.paint(colorBold, name))
+	for _, detail := range node.GetDetails() {
+		message := strings.ReplaceAll(detail.Message, "\n", "\n      ")
+		switch detail.Name {
+		case engine.ASSERT, engine.PANIC, engine.TIMEOUT:
+			fmt.Fprintf(h.writer, "    %s %s\n", h.paint(colorRed, detail.Name+":"), message)
+		default:
+			fmt.Fprintf(h.writer, "    %s %s\n", h.paint(colorGray, detail.Name+":"), message)
+		}
+	}
+}
+
+func (h *consoleHandler) paint(color string, text string) string {
+	if !h.color {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// casePath names a case together with its feature, as "feature › case".
+func casePath(testCase *engine.Assertion) string {
+	if feature := featureName(testCase.Parent()); feature != "" {
+		return feature + " › " + testCase.Name()
+	}
+	return testCase.Name()
+}
+
+func isTerminal(writer io.Writer) bool {
+	f, ok := writer.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+func TestConsoleHandler(t *testing.T) {
+	var buf bytes.Buffer
+	reportSuite(NewConsoleHandler(&buf)).Run()
+
+	out := buf.String()
+	for _, want := range []string{
+		"✓ feature › pass",
+		"✗ feature › fail",
+		"! feature › panic",
+		"- feature › ignore",
+		"Failures:",
+		"1) feature › fail",
+		"Assert: Fail, because expected",
+		"2) feature › panic",
+		"Panic: Case panicked: boom",
+		"FAILED 4 cases: 1 passed, 1 failed, 1 errored, 1 ignored in ",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "\033[") {
+		t.Error("output to a buffer is colored")
+	}
+}
+
+func TestConsoleHandlerBrokenRun(t *testing.T) {
+	var buf bytes.Buffer
+	suite := reportSuite(NewConsoleHandler(&buf))
+	suite.Features[0].TestCases = suite.Features[0].TestCases[:1]
+	suite.Features[0].AfterAll = func(assertion *engine.Assertion) { panic("teardown") }
+	suite.Run()
+	for _, want := range []string{
+		"Failures:",
+		"1) feature",
+		"Panic: AfterAll panicked: teardown",
+		"FAILED 1 cases: 1 passed, 0 failed, 0 ignored in ",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	reportSuite(NewConsoleHandler(&buf)).Run("((")
+	for _, want := range []string{
+		"1) suite",
+		"Result: Cannot run suite suite: invalid tag expression",
+		"FAILED 0 cases: ",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
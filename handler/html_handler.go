@@ -0,0 +1,277 @@
+package handler
+
+import (
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+var _ engine.MessageHandler = (*htmlHandler)(nil)
+var _ engine.FinishHandler = (*htmlHandler)(nil)
+
+// htmlHandler collects finished cases and writes them as a single static
+// HTML page, with styles and scripts inlined so it can be mailed or archived
+// as is.
+type htmlHandler struct {
+	filename string
+	mu       sync.Mutex
+	cases    []*engine.Assertion
+}
+
+func NewHTMLHandler(filename string) *htmlHandler {
+	if filename == "" {
+		filename = "report.html"
+	}
+	return &htmlHandler{filename: filename, cases: make([]*engine.Assertion, 0)}
+}
+
+func (h *htmlHandler) Send(assertion *engine.Assertion) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cases = append(h.cases, assertion)
+}
+
+func (h *htmlHandler) OnFinish(root *engine.Assertion) error {
+	return h.Write()
+}
+
+// Write writes the report of every case received so far. Use it when
+// driving the handler through engine.StartListener instead of a TestSuite.
+func (h *htmlHandler) Write() error {
+	h.mu.Lock()
+	cases := make([]*engine.Assertion, len(h.cases))
+	copy(cases, h.cases)
+	h.mu.Unlock()
+
+	if dir := filepath.Dir(h.filename); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(h.filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return htmlTemplate.Execute(f, buildHTMLReport(cases))
+}
+
+type htmlReport struct {
+	Name      string
+	Generated string
+	Duration  string
+	Tags      []string
+	engine.Counts
+	Features []htmlFeature
+	Slowest  []htmlSlowCase
+}
+
+type htmlSlowCase struct {
+	Name     string
+	Result   string
+	Duration string
+}
+
+type htmlFeature struct {
+	Name     string
+	Result   string
+	Duration string
+	Hooks    string
+	engine.Counts
+	Cases []htmlCase
+}
+
+type htmlCase struct {
+	Name     string
+	Result   string
+	Duration string
+	Hooks    string
+	Tags     []string
+	Attempts int
+	Flaky    bool
+	Details  []htmlDetail
+}
+
+type htmlDetail struct {
+	Name    string
+	Message string
+	Time    string
+	Capture bool
+	Failure bool
+}
+
+func buildHTMLReport(cases []*engine.Assertion) *htmlReport {
+	report := &htmlReport{Name: "Sparkle", Generated: time.Now().Format("2006-01-02 15:04:05")}
+	tags := make(map[string]bool)
+	groups := groupByFeature(cases)
+	for _, group := range groups {
+		if group.feature != nil && group.feature.Parent() != nil {
+			report.Name = group.feature.Parent().Name()
+		}
+		feature := htmlFeature{Name: featureName(group.feature), Hooks: hookSummary(group.feature)}
+		if group.feature != nil {
+			feature.Duration = formatDuration(group.feature.Duration())
+		}
+		feature.Counts = countResults(group.cases)
+		for _, testCase := range group.cases {
+			for _, tag := range testCase.Tags() {
+				tags[tag] = true
+			}
+			feature.Cases = append(feature.Cases, htmlCase{
+				Name:     testCase.Name(),
+				Result:   resultClass(testCase.Result()),
+				Duration: formatDuration(testCase.Duration()),
+				Hooks:    hookSummary(testCase),
+				Tags:     testCase.Tags(),
+				Attempts: testCase.Attempts(),
+				Flaky:    testCase.Flaky(),
+				Details:  htmlDetails(testCase.GetDetails()),
+			})
+		}
+		if group.feature != nil {
+			feature.Result = resultClass(group.feature.Result())
+		}
+		report.Features = append(report.Features, feature)
+	}
+	report.Counts = countResults(cases)
+	report.Duration = formatDuration(runDuration(groups))
+	for _, testCase := range slowest(cases, 10) {
+		report.Slowest = append(report.Slowest, htmlSlowCase{
+			Name:     casePath(testCase),
+			Result:   resultClass(testCase.Result()),
+			Duration: formatDuration(testCase.Duration()),
+		})
+	}
+	for tag := range tags {
+		report.Tags = append(report.Tags, tag)
+	}
+	sort.Strings(report.Tags)
+	return report
+}
+
+func htmlDetails(details []engine.Detail) []htmlDetail {
+	list := make([]htmlDetail, 0, len(details))
+	for _, detail := range details {
+		list = append(list, htmlDetail{
+			Name:    detail.Name,
+			Message: detail.Message,
+			Time:    detail.RecordTime.Format("15:04:05.000"),
+			Capture: isCapture(detail),
+			Failure: detail.Name == engine.ASSERT || detail.Name == engine.PANIC || detail.Name == engine.TIMEOUT,
+		})
+	}
+	return list
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"join": strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Name}} - Test Report</title>
+<style>
+body{font-family:-apple-system,"Segoe UI",Helvetica,Arial,sans-serif;margin:0;color:#24292e;background:#f6f8fa}
+header{background:#24292e;color:#fff;padding:16px 24px}
+header h1{margin:0;font-size:22px}
+header p{margin:4px 0 0;color:#c8c8c8;font-size:13px}
+main{padding:16px 24px}
+.counts span{display:inline-block;margin-right:12px;padding:4px 10px;border-radius:12px;font-weight:600;font-size:13px}
+.total{background:#e1e4e8}
+.pass{color:#22863a}.fail{color:#cb2431}.error{color:#b08800}.skipped,.ignore,.notrun{color:#6a737d}
+.counts .pass{background:#dcffe4}.counts .fail{background:#ffdce0}.counts .error{background:#fff5b1}.counts .skipped,.counts .ignore{background:#f1f1f1}
+.filters{margin:16px 0;font-size:14px}
+.filters label{margin-right:12px}
+details{background:#fff;border:1px solid #e1e4e8;border-radius:6px;margin:8px 0}
+summary{cursor:pointer;padding:8px 12px;list-style-position:inside}
+.feature>summary{font-weight:600}
+.feature>.body{padding:0 12px 8px}
+.case{margin:6px 0}
+.case.fail{border-left:4px solid #cb2431}.case.error{border-left:4px solid #dbab09}.case.pass{border-left:4px solid #22863a}.case.skipped,.case.ignore,.case.notrun{border-left:4px solid #959da5}
+.badge{display:inline-block;min-width:48px;text-align:center;font-size:12px;font-weight:600;text-transform:uppercase}
+.meta{color:#6a737d;font-size:12px;margin-left:8px}
+.tag{display:inline-block;background:#f1f8ff;color:#0366d6;border-radius:10px;padding:0 8px;font-size:12px;margin-left:4px}
+table{border-collapse:collapse;width:100%;font-size:13px}
+td{border-top:1px solid #eaecef;padding:4px 12px;vertical-align:top}
+td.time{color:#6a737d;white-space:nowrap;width:1%}
+td.name{font-weight:600;white-space:nowrap;width:1%}
+tr.failure td.message{color:#cb2431}
+pre{margin:0;white-space:pre-wrap;word-break:break-all;font-size:12px}
+.capture pre{background:#f6f8fa;padding:6px;border-radius:4px}
+.hidden{display:none}
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Name}}</h1>
+<p>Generated {{.Generated}}, ran for {{.Duration}}</p>
+</header>
+<main>
+<div class="counts">
+<span class="total">{{.Total}} total</span>
+<span class="pass">{{.Pass}} passed</span>
+<span class="fail">{{.Fail}} failed</span>
+<span class="error">{{.Error}} errored</span>
+<span class="skipped">{{.Skipped}} skipped</span>
+<span class="ignore">{{.Ignore}} ignored</span>
+</div>
+<div class="filters">
+<label><input type="checkbox" class="result-filter" value="pass" checked> Pass</label>
+<label><input type="checkbox" class="result-filter" value="fail" checked> Fail</label>
+<label><input type="checkbox" class="result-filter" value="error" checked> Error</label>
+<label><input type="checkbox" class="result-filter" value="skipped" checked> Skipped</label>
+<label><input type="checkbox" class="result-filter" value="ignore" checked> Ignore</label>
+<label>Tag <select id="tag-filter"><option value="">all</option>{{range .Tags}}<option value="{{.}}">{{.}}</option>{{end}}</select></label>
+</div>
+{{if .Slowest}}
+<details class="slowest">
+<summary>Slowest cases</summary>
+<table>
+{{range .Slowest}}<tr><td class="time">{{.Duration}}</td><td class="message"><span class="badge {{.Result}}">{{.Result}}</span> {{.Name}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}
+{{range .Features}}
+<details class="feature" open>
+<summary><span class="badge {{.Result}}">{{.Result}}</span> {{.Name}}<span class="meta">{{.Total}} cases, {{.Pass}} passed, {{.Fail}} failed, {{.Error}} errored, {{.Skipped}} skipped, {{.Ignore}} ignored, {{.Duration}}{{if .Hooks}} ({{.Hooks}}){{end}}</span></summary>
+<div class="body">
+{{range .Cases}}
+<details class="case {{.Result}}" data-result="{{.Result}}" data-tags="{{join .Tags " "}}">
+<summary><span class="badge {{.Result}}">{{.Result}}</span> {{.Name}}{{range .Tags}}<span class="tag">{{.}}</span>{{end}}<span class="meta">{{.Duration}}{{if .Hooks}} ({{.Hooks}}){{end}}{{if gt .Attempts 1}}, {{.Attempts}} attempts{{if .Flaky}}, flaky{{end}}{{end}}</span></summary>
+<table>
+{{range .Details}}<tr class="{{if .Failure}}failure{{end}}{{if .Capture}} capture{{end}}"><td class="time">{{.Time}}</td><td class="name">{{.Name}}</td><td class="message"><pre>{{.Message}}</pre></td></tr>
+{{end}}</table>
+</details>
+{{end}}
+</div>
+</details>
+{{end}}
+</main>
+<script>
+(function () {
+  function apply() {
+    var results = {};
+    document.querySelectorAll(".result-filter").forEach(function (box) { results[box.value] = box.checked; });
+    var tag = document.getElementById("tag-filter").value;
+    document.querySelectorAll(".case").forEach(function (el) {
+      var tags = el.getAttribute("data-tags").split(" ");
+      var show = results[el.getAttribute("data-result")] && (tag === "" || tags.indexOf(tag) >= 0);
+      el.classList.toggle("hidden", !show);
+    });
+    document.querySelectorAll(".feature").forEach(function (el) {
+      el.classList.toggle("hidden", el.querySelectorAll(".case:not(.hidden)").length === 0);
+    });
+  }
+  document.querySelectorAll(".result-filter").forEach(function (box) { box.addEventListener("change", apply); });
+  document.getElementById("tag-filter").addEventListener("change", apply);
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHTMLHandler(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "report.html")
+	suite := reportSuite(NewHTMLHandler(filename))
+	if summary := suite.Run(); len(summary.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", summary.Errors)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, want := range []string{
+		"<h1>suite</h1>",
+		"4 total", "1 passed", "1 failed", "1 errored", "1 ignored",
+		`data-result="fail"`, `data-tags="smoke"`,
+		`<option value="smoke">smoke</option>`,
+		"Fail, because expected",
+		"GET /users?name=&lt;b&gt;&amp;rate=100%25",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	for _, external := range []string{"<link", "src=", "http://", "https://"} {
+		if strings.Contains(page, external) {
+			t.Errorf("report loads an external asset: %q", external)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+var _ engine.MessageHandler = (*junitHandler)(nil)
+var _ engine.FinishHandler = (*junitHandler)(nil)
+
+// junitHandler collects finished cases and writes them as a JUnit XML
+// report, one testsuite per feature, for CI systems such as Jenkins and
+// GitLab. A feature or suite that failed on its own, in a hook or a
+// cleanup, is reported as an extra testcase named after the failed hooks.
+type junitHandler struct {
+	filename string
+	mu       sync.Mutex
+	cases    []*engine.Assertion
+	root     *engine.Assertion
+}
+
+func NewJUnitHandler(filename string) *junitHandler {
+	if filename == "" {
+		filename = "junit.xml"
+	}
+	return &junitHandler{filename: filename, cases: make([]*engine.Assertion, 0)}
+}
+
+func (h *junitHandler) Send(assertion *engine.Assertion) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.cases = append(h.cases, assertion)
+}
+
+func (h *junitHandler) OnFinish(root *engine.Assertion) error {
+	h.mu.Lock()
+	h.root = root
+	h.mu.Unlock()
+	return h.Write()
+}
+
+// Write writes the report of every case received so far. Use it when
+// driving the handler through engine.StartListener instead of a TestSuite.
+func (h *junitHandler) Write() error {
+	h.mu.Lock()
+	cases := make([]*engine.Assertion, len(h.cases))
+	copy(cases, h.cases)
+	root := h.root
+	h.mu.Unlock()
+
+	report := buildJUnitReport(cases, root)
+	if dir := filepath.Dir(h.filename); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(h.filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.WriteString(xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(f)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err = f.WriteString("\n")
+	return err
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr,omitempty"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       string           `xml:"time,attr"`
+	Timestamp  string           `xml:"timestamp,attr,omitempty"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Cases      []junitTestCase  `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string           `xml:"name,attr"`
+	Classname  string           `xml:"classname,attr"`
+	Time       string           `xml:"time,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Failure    *junitFailure    `xml:"failure,omitempty"`
+	Error      *junitFailure    `xml:"error,omitempty"`
+	Skipped    *junitSkipped    `xml:"skipped,omitempty"`
+	SystemOut  string           `xml:"system-out,omitempty"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr,omitempty"`
+}
+
+func buildJUnitReport(cases []*engine.Assertion, root *engine.Assertion) *junitTestSuites {
+	report := &junitTestSuites{Suites: make([]junitTestSuite, 0)}
+	groups := groupByFeature(cases)
+	index := make(map[*engine.Assertion]int)
+	for _, group := range groups {
+		if report.Name == "" && group.feature != nil && group.feature.Parent() != nil {
+			report.Name = group.feature.Parent().Name()
+		}
+		suite := junitTestSuite{Name: featureName(group.feature)}
+		if group.feature != nil {
+			suite.Time = seconds(group.feature.Duration())
+			suite.Timestamp = group.feature.StartTime().Format("2006-01-02T15:04:05")
+			suite.Properties = junitHookProperties(group.feature, nil)
+		}
+		for _, testCase := range group.cases {
+			junitCase := junitTestCase{
+				Name:       testCase.Name(),
+				Classname:  suite.Name,
+				Time:       seconds(testCase.Duration()),
+				Properties: junitCaseProperties(testCase),
+				SystemOut:  detailLog(testCase.GetDetails()),
+			}
+			switch testCase.Result() {
+			case engine.FAIL:
+				details, _ := failures(testCase)
+				junitCase.Failure = junitFailureOf(details)
+				suite.Failures++
+			case engine.ERROR:
+				details, _ := failures(testCase)
+				junitCase.Error = junitFailureOf(details)
+				suite.Errors++
+			case engine.IGNORE, engine.SKIPPED:
+				junitCase.Skipped = &junitSkipped{Message: skipReason(testCase)}
+				suite.Skipped++
+			}
+			suite.Tests++
+			suite.Cases = append(suite.Cases, junitCase)
+		}
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Errors += suite.Errors
+		report.Skipped += suite.Skipped
+		index[group.feature] = len(report.Suites)
+		report.Suites = append(report.Suites, suite)
+	}
+	report.Time = seconds(runDuration(groups))
+
+	for _, node := range brokenNodes(root) {
+		i, ok := index[node]
+		if !ok {
+			i = len(report.Suites)
+			index[node] = i
+			report.Suites = append(report.Suites, junitTestSuite{
+				Name:      node.Name(),
+				Time:      seconds(node.Duration()),
+				Timestamp: node.StartTime().Format("2006-01-02T15:04:05"),
+			})
+		}
+		suite := &report.Suites[i]
+		name := failedHooks(node)
+		if name == "" {
+			name = node.Name()
+		}
+		var elapsed time.Duration
+		for _, hook := range node.HookTimings() {
+			if hook.Result.Failed() {
+				elapsed += hook.Duration()
+			}
+		}
+		junitCase := junitTestCase{
+			Name:      name,
+			Classname: suite.Name,
+			Time:      seconds(elapsed),
+			SystemOut: detailLog(node.GetDetails()),
+		}
+		if details, crashed := failures(node); crashed {
+			junitCase.Error = junitFailureOf(details)
+			suite.Errors++
+			report.Errors++
+		} else {
+			junitCase.Failure = junitFailureOf(details)
+			suite.Failures++
+			report.Failures++
+		}
+		suite.Tests++
+		report.Tests++
+		suite.Cases = append(suite.Cases, junitCase)
+	}
+	if report.Name == "" && root != nil && root.NodeType() == engine.TEST_SUITE {
+		report.Name = root.Name()
+	}
+	return report
+}
+
+func junitCaseProperties(testCase *engine.Assertion) *junitProperties {
+	properties := make([]junitProperty, 0)
+	for _, tag := range testCase.Tags() {
+		properties = append(properties, junitProperty{Name: "tag", Value: tag})
+	}
+	if attempts := testCase.Attempts(); attempts > 1 {
+		properties = append(properties, junitProperty{Name: "attempts", Value: strconv.Itoa(attempts)})
+		properties = append(properties, junitProperty{Name: "flaky", Value: strconv.FormatBool(testCase.Flaky())})
+	}
+	return junitHookProperties(testCase, properties)
+}
+
+// junitHookProperties appends the time in seconds of every hook run for
+// node, as "hook.BeforeEach" properties.
+func junitHookProperties(node *engine.Assertion, properties []junitProperty) *junitProperties {
+	for _, hook := range node.HookTimings() {
+		properties = append(properties, junitProperty{Name: "hook." + hook.Name, Value: seconds(hook.Duration())})
+	}
+	if len(properties) == 0 {
+		return nil
+	}
+	return &junitProperties{Properties: properties}
+}
+
+func junitFailureOf(details []engine.Detail) *junitFailure {
+	if len(details) == 0 {
+		return &junitFailure{Message: "failed", Type: engine.RESULT}
+	}
+	messages := make([]string, 0, len(details))
+	for _, detail := range details {
+		messages = append(messages, detail.Message)
+	}
+	return &junitFailure{
+		Message: strings.SplitN(details[0].Message, "\n", 2)[0],
+		Type:    details[0].Name,
+		Text:    strings.Join(messages, "\n\n"),
+	}
+}
+
+func detailLog(details []engine.Detail) string {
+	lines := make([]string, 0, len(details))
+	for _, detail := range details {
+		lines = append(lines, fmt.Sprintf("%s %s: %s", detail.RecordTime.Format(time.RFC3339Nano), detail.Name, detail.Message))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+func TestJUnitHandler(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "reports", "junit.xml")
+	suite := reportSuite(NewJUnitHandler(filename))
+	suite.Features[0].AfterAll = func(assertion *engine.Assertion) { panic("teardown") }
+	if summary := suite.Run(); len(summary.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", summary.Errors)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Name != "suite" || report.Tests != 5 || report.Failures != 1 || report.Errors != 2 || report.Skipped != 1 {
+		t.Fatalf("unexpected totals: %+v", report)
+	}
+	if len(report.Suites) != 1 || report.Suites[0].Name != "feature" || len(report.Suites[0].Cases) != 5 {
+		t.Fatalf("unexpected suites: %+v", report.Suites)
+	}
+	cases := make(map[string]junitTestCase)
+	for _, c := range report.Suites[0].Cases {
+		cases[c.Name] = c
+	}
+	if pass := cases["pass"]; pass.Failure != nil || pass.Properties == nil || pass.Properties.Properties[0].Value != "smoke" {
+		t.Errorf("unexpected pass case: %+v", pass)
+	}
+	if fail := cases["fail"]; fail.Failure == nil || fail.Failure.Message != "Fail, because expected" {
+		t.Errorf("unexpected fail case: %+v", fail)
+	}
+	if crash := cases["panic"]; crash.Error == nil || crash.Error.Type != engine.PANIC {
+		t.Errorf("unexpected panic case: %+v", crash)
+	}
+	if ignore := cases["ignore"]; ignore.Skipped == nil {
+		t.Errorf("unexpected ignore case: %+v", ignore)
+	}
+	if teardown := cases["AfterAll"]; teardown.Error == nil || teardown.Error.Message != "AfterAll panicked: teardown" {
+		t.Errorf("unexpected AfterAll case: %+v", teardown)
+	}
+
+	reportSuite(NewJUnitHandler(filename)).Run("((")
+	data, err = os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report = junitTestSuites{}
+	if err := xml.Unmarshal(data, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Tests != 1 || report.Failures != 1 || len(report.Suites) != 1 || report.Suites[0].Cases[0].Name != "suite" {
+		t.Errorf("unexpected report of a run that could not start: %+v", report)
+	}
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+var _ engine.MessageHandler = (*ndjsonHandler)(nil)
+var _ engine.EventHandler = (*ndjsonHandler)(nil)
+var _ engine.FinishHandler = (*ndjsonHandler)(nil)
+var _ outputCloser = (*ndjsonHandler)(nil)
+
+// ndjsonHandler writes every event of a run as one JSON object per line,
+// as it happens.
+type ndjsonHandler struct {
+	mu      sync.Mutex
+	writer  io.Writer
+	encoder *json.Encoder
+	closer  io.Closer
+	err     error
+}
+
+func NewNDJSONHandler(writer io.Writer) *ndjsonHandler {
+	return &ndjsonHandler{writer: writer, encoder: json.NewEncoder(writer)}
+}
+
+// NewNDJSONFileHandler writes the events to a file, which is closed once
+// the suite finishes.
+func NewNDJSONFileHandler(filename string) (*ndjsonHandler, error) {
+	if dir := filepath.Dir(filename); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	handler := NewNDJSONHandler(f)
+	handler.closer = f
+	return handler, nil
+}
+
+// Send does nothing: finished cases are already written as case_end events.
+func (h *ndjsonHandler) Send(assertion *engine.Assertion) {}
+
+type ndjsonEvent struct {
+	Type     engine.EventType `json:"type"`
+	Time     time.Time        `json:"time"`
+	Suite    string           `json:"suite,omitempty"`
+	Feature  string           `json:"feature,omitempty"`
+	Case     string           `json:"case,omitempty"`
+	Tags     []string         `json:"tags,omitempty"`
+	Hook     string           `json:"hook,omitempty"`
+	Result   string           `json:"result,omitempty"`
+	Attempts int              `json:"attempts,omitempty"`
+	// DurationMs is set on end events.
+	DurationMs *int64        `json:"duration_ms,omitempty"`
+	Detail     *ndjsonDetail `json:"detail,omitempty"`
+}
+
+type ndjsonDetail struct {
+	Name    string    `json:"name"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+func (h *ndjsonHandler) OnEvent(event engine.Event) {
+	record := ndjsonEvent{Type: event.Type, Time: event.Time, Hook: event.Hook}
+	for node := event.Node; node != nil; node = node.Parent() {
+		switch node.NodeType() {
+		case engine.TEST_SUITE:
+			record.Suite = node.Name()
+		case engine.TEST_FEATURE:
+			record.Feature = node.Name()
+		case engine.TEST_CASE:
+			record.Case = node.Name()
+		}
+	}
+	switch event.Type {
+	case engine.EVENT_CASE_START, engine.EVENT_CASE_END:
+		record.Tags = event.Node.Tags()
+	}
+	switch event.Type {
+	case engine.EVENT_SUITE_END, engine.EVENT_FEATURE_END, engine.EVENT_HOOK_END, engine.EVENT_CASE_END:
+		record.Result = event.Result.String()
+		milliseconds := event.Duration.Milliseconds()
+		record.DurationMs = &milliseconds
+	}
+	if event.Type == engine.EVENT_CASE_END {
+		record.Attempts = event.Node.Attempts()
+	}
+	if event.Detail != nil {
+		record.Detail = &ndjsonDetail{Name: event.Detail.Name, Message: event.Detail.Message, Time: event.Detail.RecordTime}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.err != nil {
+		return
+	}
+	// Encode terminates every object with a newline.
+	h.err = h.encoder.Encode(record)
+}
+
+// OnFinish closes the file of NewNDJSONFileHandler and returns the first
+// error met while writing.
+func (h *ndjsonHandler) OnFinish(root *engine.Assertion) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.closeOutput(); err != nil && h.err == nil {
+		h.err = err
+	}
+	return h.err
+}
+
+func (h *ndjsonHandler) closeOutput() error {
+	return closeOnce(&h.closer)
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+func TestNDJSONHandler(t *testing.T) {
+	var buf bytes.Buffer
+	suite := &engine.TestSuite{
+		Name: "suite",
+		Features: []*engine.TestFeature{
+			{
+				Name:      "feature",
+				AfterEach: func(assertion *engine.Assertion) {},
+				TestCases: []engine.TestCase{
+					{
+						Name: "fail",
+						Tag:  []string{"smoke"},
+						Case: func(assertion *engine.Assertion, args ...interface{}) {
+							assertion.AssertFail("expected")
+						},
+					},
+				},
+			},
+		},
+		Handlers: []engine.MessageHandler{NewNDJSONHandler(&buf)},
+	}
+	if summary := suite.Run(); len(summary.Errors) != 0 {
+		t.Fatalf("unexpected errors: %v", summary.Errors)
+	}
+
+	events := make([]ndjsonEvent, 0)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event ndjsonEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	if len(events) < 6 {
+		t.Fatalf("expected at least 6 events, got %d", len(events))
+	}
+	if first, last := events[0], events[len(events)-1]; first.Type != engine.EVENT_SUITE_START || last.Type != engine.EVENT_SUITE_END || last.Result != "Fail" {
+		t.Fatalf("unexpected first and last events: %+v %+v", first, last)
+	}
+	seen := make(map[engine.EventType]ndjsonEvent)
+	for _, event := range events {
+		if event.Type == engine.EVENT_DETAIL && event.Detail.Name != engine.ASSERT {
+			continue
+		}
+		seen[event.Type] = event
+	}
+	if end := seen[engine.EVENT_CASE_END]; end.Suite != "suite" || end.Feature != "feature" || end.Case != "fail" || end.Result != "Fail" || end.Attempts != 1 || len(end.Tags) != 1 {
+		t.Errorf("unexpected case_end: %+v", end)
+	}
+	if hook := seen[engine.EVENT_HOOK_END]; hook.Hook != "AfterEach" || hook.Result != "Pass" {
+		t.Errorf("unexpected hook_end: %+v", hook)
+	}
+	if detail := seen[engine.EVENT_DETAIL]; detail.Detail == nil || detail.Detail.Message != "Fail, because expected" {
+		t.Errorf("unexpected detail: %+v", detail)
+	}
+}
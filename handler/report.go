@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+// featureCases groups finished cases by the feature they belong to, keeping
+// the order in which features and cases arrived.
+type featureCases struct {
+	feature *engine.Assertion
+	cases   []*engine.Assertion
+}
+
+func groupByFeature(cases []*engine.Assertion) []*featureCases {
+	groups := make([]*featureCases, 0)
+	index := make(map[*engine.Assertion]*featureCases)
+	for _, testCase := range cases {
+		feature := testCase.Parent()
+		group, ok := index[feature]
+		if !ok {
+			group = &featureCases{feature: feature}
+			index[feature] = group
+			groups = append(groups, group)
+		}
+		group.cases = append(group.cases, testCase)
+	}
+	return groups
+}
+
+func featureName(feature *engine.Assertion) string {
+	if feature == nil {
+		return ""
+	}
+	return feature.Name()
+}
+
+// runDuration returns how long the run took: the duration of the suite when
+// the cases ran in one, else the sum of the durations of their features.
+func runDuration(groups []*featureCases) time.Duration {
+	var total time.Duration
+	for _, group := range groups {
+		if group.feature == nil {
+			continue
+		}
+		if suite := group.feature.Parent(); suite != nil {
+			return suite.Duration()
+		}
+		total += group.feature.Duration()
+	}
+	return total
+}
+
+// hookSummary lists the hooks run for node with their durations, and the
+// result of those that did not pass, as "BeforeEach 3ms, AfterEach 1ms Fail".
+func hookSummary(node *engine.Assertion) string {
+	if node == nil {
+		return ""
+	}
+	hooks := make([]string, 0)
+	for _, hook := range node.HookTimings() {
+		summary := hook.Name + " " + formatDuration(hook.Duration())
+		if hook.Result != engine.PASS {
+			summary += " " + hook.Result.String()
+		}
+		hooks = append(hooks, summary)
+	}
+	return strings.Join(hooks, ", ")
+}
+
+// slowest returns up to n cases that ran longest, slowest first.
+func slowest(cases []*engine.Assertion, n int) []*engine.Assertion {
+	ran := make([]*engine.Assertion, 0, len(cases))
+	for _, testCase := range cases {
+		if !skipped(testCase.Result()) {
+			ran = append(ran, testCase)
+		}
+	}
+	sort.SliceStable(ran, func(i, j int) bool {
+		return ran[i].Duration() > ran[j].Duration()
+	})
+	if len(ran) > n {
+		ran = ran[:n]
+	}
+	return ran
+}
+
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Millisecond).String()
+}
+
+// failures returns the Details explaining why an Assertion failed, and
+// whether it crashed, ending ERROR, rather than failed an assert. Those are
+// its asserts, panics and timeouts, or else the runner's results, as for a
+// case with a broken dependency or a failed BeforeAll.
+func failures(assertion *engine.Assertion) ([]engine.Detail, bool) {
+	found := make([]engine.Detail, 0)
+	results := make([]engine.Detail, 0)
+	for _, detail := range assertion.GetDetails() {
+		switch detail.Name {
+		case engine.PANIC, engine.TIMEOUT, engine.ASSERT:
+			found = append(found, detail)
+		case engine.RESULT:
+			results = append(results, detail)
+		}
+	}
+	if len(found) == 0 {
+		found = results
+	}
+	return found, assertion.Result() == engine.ERROR
+}
+
+// brokenNodes returns the features under root, then root itself, that
+// failed on their own rather than through one of their cases: in a hook, a
+// cleanup or before any case could run. Their Details say why.
+func brokenNodes(root *engine.Assertion) []*engine.Assertion {
+	broken := make([]*engine.Assertion, 0)
+	if root == nil {
+		return broken
+	}
+	nodes := make([]*engine.Assertion, 0)
+	if root.NodeType() == engine.TEST_SUITE {
+		nodes = append(nodes, root.Children()...)
+	}
+	nodes = append(nodes, root)
+	for _, node := range nodes {
+		if details, _ := failures(node); node.Result().Failed() && len(details) > 0 {
+			broken = append(broken, node)
+		}
+	}
+	return broken
+}
+
+// failedHooks names the hooks of node that failed, as "AfterAll" or
+// "BeforeAll, Cleanup", or is empty when node failed outside of its hooks.
+func failedHooks(node *engine.Assertion) string {
+	hooks := make([]string, 0)
+	for _, hook := range node.HookTimings() {
+		if hook.Result.Failed() {
+			hooks = append(hooks, hook.Name)
+		}
+	}
+	return strings.Join(hooks, ", ")
+}
+
+// isCapture reports whether a Detail holds a dumped request or response.
+func isCapture(detail engine.Detail) bool {
+	switch detail.Name {
+	case engine.HTTP_REQUEST, engine.HTTP_RESPONSE, engine.GRPC_REQUEST, engine.GRPC_RESPONSE:
+		return true
+	}
+	return false
+}
+
+// countResults counts the results of cases.
+func countResults(cases []*engine.Assertion) engine.Counts {
+	var counts engine.Counts
+	for _, testCase := range cases {
+		c := testCase.Counts()
+		counts.Total += c.Total
+		counts.Pass += c.Pass
+		counts.Fail += c.Fail
+		counts.Error += c.Error
+		counts.Skipped += c.Skipped
+		counts.Ignore += c.Ignore
+		counts.NotRun += c.NotRun
+	}
+	return counts
+}
+
+// resultClass names a result in lower case, as the HTML report's filters
+// and styles do.
+func resultClass(result engine.Result) string {
+	switch result {
+	case engine.PASS:
+		return "pass"
+	case engine.FAIL:
+		return "fail"
+	case engine.ERROR:
+		return "error"
+	case engine.SKIPPED:
+		return "skipped"
+	case engine.IGNORE:
+		return "ignore"
+	default:
+		return "notrun"
+	}
+}
+
+// skipReason says why a case that did not run was left out: the message
+// of its Skip Detail, or else "skipped" or "ignored".
+func skipReason(assertion *engine.Assertion) string {
+	if assertion.Result() != engine.SKIPPED {
+		return "ignored"
+	}
+	for _, detail := range assertion.GetDetails() {
+		if detail.Name == engine.SKIP {
+			return detail.Message
+		}
+	}
+	return "skipped"
+}
+
+// skipped reports whether a case did not run, being ignored or skipped.
+func skipped(result engine.Result) bool {
+	return result == engine.IGNORE || result == engine.SKIPPED
+}
@@ -0,0 +1,42 @@
+package handler
+
+import "github.com/jimmyseraph/sparkle/engine"
+
+// reportSuite is a feature with a passing, a failing, a panicking and an
+// ignored case, the run every report handler is tested against. The
+// passing case is tagged and captures an HTTP exchange.
+func reportSuite(handlers ...engine.MessageHandler) *engine.TestSuite {
+	return &engine.TestSuite{
+		Name: "suite",
+		Features: []*engine.TestFeature{
+			{
+				Name: "feature",
+				TestCases: []engine.TestCase{
+					{
+						Name: "pass",
+						Tag:  []string{"smoke"},
+						Case: func(assertion *engine.Assertion, args ...interface{}) {
+							assertion.Capture(engine.HTTP_REQUEST, "GET /users?name=<b>&rate=100%25")
+							assertion.Capture(engine.HTTP_RESPONSE, "HTTP/1.1 200 OK")
+							assertion.AssertEquals(2, 1+1, "sum")
+						},
+					},
+					{
+						Name: "fail",
+						Case: func(assertion *engine.Assertion, args ...interface{}) {
+							assertion.AssertFail("expected")
+						},
+					},
+					{
+						Name: "panic",
+						Case: func(assertion *engine.Assertion, args ...interface{}) {
+							panic("boom")
+						},
+					},
+					{Name: "ignore", Ignore: true},
+				},
+			},
+		},
+		Handlers: handlers,
+	}
+}
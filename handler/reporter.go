@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+// REPORTER_ENV names the environment variable FromEnv reads.
+const REPORTER_ENV = "SPARKLE_REPORTER"
+
+// Reporters lists the names New accepts.
+func Reporters() []string {
+	return []string{"allure", "console", "html", "junit", "ndjson", "tap", "zap"}
+}
+
+// New returns the reporter called name. output is the file, or for allure
+// the directory, it writes to. Empty selects the reporter's default:
+// standard output for console, tap and ndjson, and report.html, junit.xml
+// and allure-results for the others. "-" is standard output. Files of the
+// streaming reporters are closed once the suite finishes.
+func New(name string, output string) (engine.MessageHandler, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "console":
+		writer, closer, err := openOutput(output)
+		if err != nil {
+			return nil, err
+		}
+		handler := NewConsoleHandler(writer)
+		handler.closer = closer
+		return handler, nil
+	case "tap":
+		writer, closer, err := openOutput(output)
+		if err != nil {
+			return nil, err
+		}
+		handler := NewTAPHandler(writer)
+		handler.closer = closer
+		return handler, nil
+	case "ndjson":
+		if output == "" || output == "-" {
+			return NewNDJSONHandler(os.Stdout), nil
+		}
+		return NewNDJSONFileHandler(output)
+	case "junit":
+		return NewJUnitHandler(output), nil
+	case "html":
+		return NewHTMLHandler(output), nil
+	case "allure":
+		return NewAllureHandler(output), nil
+	case "zap":
+		return NewZapHandler(), nil
+	}
+	return nil, fmt.Errorf("unknown reporter %q, expected one of %s", name, strings.Join(Reporters(), ", "))
+}
+
+// Parse returns the reporters of a comma separated list of names, each
+// optionally followed by "=" and its output, as in
+// "console,junit=reports/junit.xml". On an error it closes the files of the
+// reporters it created already.
+func Parse(spec string) ([]engine.MessageHandler, error) {
+	handlers := make([]engine.MessageHandler, 0)
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		parts := strings.SplitN(item, "=", 2)
+		output := ""
+		if len(parts) == 2 {
+			output = strings.TrimSpace(parts[1])
+		}
+		handler, err := New(parts[0], output)
+		if err != nil {
+			for _, opened := range handlers {
+				if closer, ok := opened.(outputCloser); ok {
+					closer.closeOutput()
+				}
+			}
+			return nil, err
+		}
+		handlers = append(handlers, handler)
+	}
+	return handlers, nil
+}
+
+// FromEnv returns the reporters listed in SPARKLE_REPORTER, in the format
+// of Parse, and the console reporter when it is not set.
+func FromEnv() ([]engine.MessageHandler, error) {
+	spec := os.Getenv(REPORTER_ENV)
+	if strings.TrimSpace(spec) == "" {
+		spec = "console"
+	}
+	return Parse(spec)
+}
+
+// outputCloser is a streaming reporter that may have opened the file it
+// writes to. closeOutput closes that file, if any; OnFinish calls it, and
+// Parse too for a reporter no run has seen.
+type outputCloser interface {
+	closeOutput() error
+}
+
+// openOutput opens a file for a streaming reporter, to be closed once the
+// suite finishes. Standard output comes with a nil closer.
+func openOutput(output string) (io.Writer, io.Closer, error) {
+	if output == "" || output == "-" {
+		return os.Stdout, nil, nil
+	}
+	if dir := filepath.Dir(output); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, nil, err
+		}
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f, nil
+}
+
+// closeOnce closes *closer, if set, and clears it.
+func closeOnce(closer *io.Closer) error {
+	if *closer == nil {
+		return nil
+	}
+	err := (*closer).Close()
+	*closer = nil
+	return err
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	dir := t.TempDir()
+	handlers, err := Parse("console, tap ,junit=" + filepath.Join(dir, "junit.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handlers) != 3 {
+		t.Fatalf("expected 3 handlers, got %d", len(handlers))
+	}
+	if _, ok := handlers[0].(*consoleHandler); !ok {
+		t.Errorf("expected a console handler, got %T", handlers[0])
+	}
+	if junit, ok := handlers[2].(*junitHandler); !ok || junit.filename != filepath.Join(dir, "junit.xml") {
+		t.Errorf("unexpected junit handler %#v", handlers[2])
+	}
+	if _, err := Parse("console,unknown"); err == nil {
+		t.Error("expected an error for an unknown reporter")
+	}
+}
+
+func TestParseCloses(t *testing.T) {
+	dir := t.TempDir()
+	console, tap := filepath.Join(dir, "console.txt"), filepath.Join(dir, "report.tap")
+	handlers, err := Parse("console=" + console + ",tap=" + tap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reportSuite(handlers...).Run()
+	for _, handler := range handlers {
+		var closed bool
+		switch h := handler.(type) {
+		case *consoleHandler:
+			closed = h.closer == nil
+		case *tapHandler:
+			closed = h.closer == nil
+		}
+		if !closed {
+			t.Errorf("%T left its file open", handler)
+		}
+	}
+	if data, err := os.ReadFile(tap); err != nil || !strings.HasSuffix(string(data), "1..4\n") {
+		t.Errorf("unexpected tap file %q, %v", data, err)
+	}
+
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Skip("cannot count open files:", err)
+	}
+	if _, err := Parse("tap=" + tap + ",unknown"); err == nil {
+		t.Fatal("expected an error for an unknown reporter")
+	}
+	if after, _ := os.ReadDir("/proc/self/fd"); len(after) > len(fds) {
+		t.Errorf("failed Parse left %d files open", len(after)-len(fds))
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv(REPORTER_ENV, "")
+	handlers, err := FromEnv()
+	if err != nil || len(handlers) != 1 {
+		t.Fatalf("unexpected default reporters %v, %v", handlers, err)
+	}
+	if _, ok := handlers[0].(*consoleHandler); !ok {
+		t.Errorf("expected the console handler by default, got %T", handlers[0])
+	}
+
+	t.Setenv(REPORTER_ENV, "tap")
+	handlers, err = FromEnv()
+	if err != nil || len(handlers) != 1 {
+		t.Fatalf("unexpected reporters %v, %v", handlers, err)
+	}
+	if _, ok := handlers[0].(*tapHandler); !ok {
+		t.Errorf("expected the tap handler, got %T", handlers[0])
+	}
+}
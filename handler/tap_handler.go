@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+var _ engine.MessageHandler = (*tapHandler)(nil)
+var _ engine.FinishHandler = (*tapHandler)(nil)
+var _ outputCloser = (*tapHandler)(nil)
+
+// tapHandler prints finished cases in TAP version 13, with a YAML block of
+// Details under every failed case. A feature or suite that failed on its
+// own, in a hook or a cleanup, is one more failed test point at the end.
+// The plan comes last, once the number of test points is known.
+type tapHandler struct {
+	mu     sync.Mutex
+	writer io.Writer
+	closer io.Closer
+	count  int
+}
+
+func NewTAPHandler(writer io.Writer) *tapHandler {
+	if writer == nil {
+		writer = os.Stdout
+	}
+	return &tapHandler{writer: writer}
+}
+
+func (h *tapHandler) Send(assertion *engine.Assertion) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header()
+	h.count++
+	description := strings.ReplaceAll(casePath(assertion), "#", "\\#")
+	switch assertion.Result() {
+	case engine.FAIL, engine.ERROR:
+		fmt.Fprintf(h.writer, "not ok %d - %s\n", h.count, description)
+		h.diagnostic(assertion)
+	case engine.IGNORE, engine.SKIPPED:
+		fmt.Fprintf(h.writer, "ok %d - %s # SKIP %s\n", h.count, description, skipReason(assertion))
+	default:
+		fmt.Fprintf(h.writer, "ok %d - %s\n", h.count, description)
+		fmt.Fprintln(h.writer, "  ---")
+		h.timings(assertion)
+		fmt.Fprintln(h.writer, "  ...")
+	}
+}
+
+func (h *tapHandler) OnFinish(root *engine.Assertion) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header()
+	for _, node := range brokenNodes(root) {
+		h.count++
+		description := node.Name()
+		if hooks := failedHooks(node); hooks != "" {
+			description += " › " + hooks
+		}
+		fmt.Fprintf(h.writer, "not ok %d - %s\n", h.count, strings.ReplaceAll(description, "#", "\\#"))
+		h.diagnostic(node)
+	}
+	_, err := fmt.Fprintf(h.writer, "1..%d\n", h.count)
+	if closeErr := h.closeOutput(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (h *tapHandler) closeOutput() error {
+	return closeOnce(&h.closer)
+}
+
+func (h *tapHandler) header() {
+	if h.count == 0 {
+		fmt.Fprintln(h.writer, "TAP version 13")
+	}
+}
+
+func (h *tapHandler) diagnostic(assertion *engine.Assertion) {
+	details, crashed := failures(assertion)
+	severity := "fail"
+	if crashed {
+		severity = "error"
+	}
+	message := "failed"
+	if len(details) > 0 {
+		message = strings.SplitN(details[0].Message, "\n", 2)[0]
+	}
+	fmt.Fprintln(h.writer, "  ---")
+	fmt.Fprintf(h.writer, "  message: %s\n", yamlQuote(message))
+	fmt.Fprintf(h.writer, "  severity: %s\n", severity)
+	h.timings(assertion)
+	fmt.Fprintln(h.writer, "  details:")
+	for _, detail := range assertion.GetDetails() {
+		fmt.Fprintf(h.writer, "    - name: %s\n", yamlQuote(detail.Name))
+		fmt.Fprintln(h.writer, "      message: |-")
+		for _, line := range strings.Split(detail.Message, "\n") {
+			fmt.Fprintf(h.writer, "        %s\n", line)
+		}
+	}
+	fmt.Fprintln(h.writer, "  ...")
+}
+
+// timings writes the duration, the attempts and the hook timings of a case
+// into its YAML block.
+func (h *tapHandler) timings(assertion *engine.Assertion) {
+	fmt.Fprintf(h.writer, "  duration_ms: %d\n", assertion.Duration().Milliseconds())
+	if attempts := assertion.Attempts(); attempts > 1 {
+		fmt.Fprintf(h.writer, "  attempts: %d\n", attempts)
+	}
+	hooks := assertion.HookTimings()
+	if len(hooks) == 0 {
+		return
+	}
+	fmt.Fprintln(h.writer, "  hooks:")
+	for _, hook := range hooks {
+		fmt.Fprintf(h.writer, "    - name: %s\n", yamlQuote(hook.Name))
+		fmt.Fprintf(h.writer, "      duration_ms: %d\n", hook.Duration().Milliseconds())
+		fmt.Fprintf(h.writer, "      result: %s\n", yamlQuote(hook.Result.String()))
+	}
+}
+
+// yamlQuote renders s as a double quoted YAML scalar.
+func yamlQuote(s string) string {
+	return fmt.Sprintf("%q", s)
+}
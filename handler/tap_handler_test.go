@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/jimmyseraph/sparkle/engine"
+)
+
+func TestTAPHandler(t *testing.T) {
+	var buf bytes.Buffer
+	reportSuite(NewTAPHandler(&buf)).Run()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != "TAP version 13" || lines[len(lines)-1] != "1..4" {
+		t.Fatalf("unexpected header or plan:\n%s", buf.String())
+	}
+	for _, want := range []string{
+		"ok 1 - feature › pass\n  ---\n  duration_ms: ",
+		"not ok 2 - feature › fail\n  ---\n  message: \"Fail, because expected\"\n  severity: fail\n",
+		"not ok 3 - feature › panic\n  ---\n  message: \"Case panicked: boom\"\n  severity: error\n",
+		"ok 4 - feature › ignore # SKIP ignored\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestTAPHandlerReasons(t *testing.T) {
+	var buf bytes.Buffer
+	suite := &engine.TestSuite{
+		Name: "suite",
+		Features: []*engine.TestFeature{
+			{
+				Name:     "feature",
+				AfterAll: func(assertion *engine.Assertion) { assertion.AssertFail("teardown") },
+				TestCases: []engine.TestCase{
+					{
+						Name: "skip",
+						Case: func(assertion *engine.Assertion, args ...interface{}) {
+							assertion.Skip("no database")
+						},
+					},
+					{
+						Name:      "orphan",
+						DependsOn: []string{"missing"},
+						Case:      func(assertion *engine.Assertion, args ...interface{}) {},
+					},
+				},
+			},
+		},
+		Handlers: []engine.MessageHandler{NewTAPHandler(&buf)},
+	}
+	suite.Run()
+
+	for _, want := range []string{
+		"ok 1 - feature › skip # SKIP Skipped, because no database\n",
+		"not ok 2 - feature › orphan\n  ---\n  message: \"Cannot run testcase orphan: testcase orphan depends on unknown testcase missing\"\n",
+		"not ok 3 - feature › AfterAll\n  ---\n  message: \"Fail, because teardown\"\n  severity: fail\n",
+		"1..3\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
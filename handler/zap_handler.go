@@ -8,6 +8,7 @@ import (
 )
 
 var _ engine.MessageHandler = (*zapHandler)(nil)
+var _ engine.FinishHandler = (*zapHandler)(nil)
 
 type zapHandler struct {
 	handler *zap.SugaredLogger
@@ -22,5 +23,30 @@ func (h *zapHandler) Send(assertion *engine.Assertion) {
 	for _, detail := range assertion.GetDetails() {
 		h.handler.Infof("%s: %s, %v", detail.Name, detail.Message, detail.RecordTime)
 	}
+	h.handler.Infow("Case finished",
+		"case", casePath(assertion),
+		"result", assertion.Result().String(),
+		"duration", formatDuration(assertion.Duration()),
+		"hooks", hookSummary(assertion),
+	)
+}
 
+// OnFinish logs the duration of every feature with its hooks, and of the
+// whole run.
+func (h *zapHandler) OnFinish(root *engine.Assertion) error {
+	for _, feature := range root.Children() {
+		h.handler.Infow("Feature finished",
+			"feature", feature.Name(),
+			"result", feature.Result().String(),
+			"duration", formatDuration(feature.Duration()),
+			"hooks", hookSummary(feature),
+		)
+	}
+	h.handler.Infow("Suite finished",
+		"suite", root.Name(),
+		"result", root.Result().String(),
+		"duration", formatDuration(root.Duration()),
+		"hooks", hookSummary(root),
+	)
+	return nil
 }
@@ -0,0 +1,371 @@
+// Package jsonpath queries decoded JSON documents with a subset of JSONPath:
+//
+//	$                 the document root, optional at the start of a path
+//	.name ['name']    an object member
+//	[2] [-1]          an array element, negative indexes count from the end
+//	[1:3]             an array slice, either bound may be omitted
+//	.* [*]            every member or element
+//	..name ..*        recursive descent
+package jsonpath
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned when a definite path does not exist in a document.
+var ErrNotFound = errors.New("jsonpath: no value at path")
+
+type stepKind int
+
+const (
+	member stepKind = iota
+	index
+	slice
+	wildcard
+)
+
+type step struct {
+	kind      stepKind
+	name      string
+	index     int
+	start     *int
+	end       *int
+	recursive bool
+}
+
+// Path is a compiled JSONPath expression.
+type Path struct {
+	raw   string
+	steps []step
+}
+
+// Compile parses a JSONPath expression.
+func Compile(path string) (*Path, error) {
+	p := &parser{input: strings.TrimSpace(path)}
+	steps, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("jsonpath: invalid path %q: %w", path, err)
+	}
+	return &Path{raw: path, steps: steps}, nil
+}
+
+func (p *Path) String() string {
+	return p.raw
+}
+
+// Definite reports whether the path selects at most one value, that is it
+// has no wildcard, slice or recursive descent.
+func (p *Path) Definite() bool {
+	for _, s := range p.steps {
+		if s.recursive || s.kind == wildcard || s.kind == slice {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns every value the path selects in doc, in document order.
+func (p *Path) Query(doc interface{}) []interface{} {
+	current := []interface{}{doc}
+	for _, s := range p.steps {
+		next := make([]interface{}, 0)
+		for _, value := range current {
+			if s.recursive {
+				for _, descendant := range descendants(value) {
+					next = append(next, s.apply(descendant)...)
+				}
+			} else {
+				next = append(next, s.apply(value)...)
+			}
+		}
+		current = next
+	}
+	return current
+}
+
+// Get returns the value a definite path selects in doc, or ErrNotFound. For
+// other paths it returns every selected value as a []interface{}.
+func (p *Path) Get(doc interface{}) (interface{}, error) {
+	values := p.Query(doc)
+	if !p.Definite() {
+		return values, nil
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("%w %s", ErrNotFound, p.raw)
+	}
+	return values[0], nil
+}
+
+// Get compiles path and returns the value it selects in doc, see Path.Get.
+func Get(doc interface{}, path string) (interface{}, error) {
+	p, err := Compile(path)
+	if err != nil {
+		return nil, err
+	}
+	return p.Get(doc)
+}
+
+// GetJSON decodes a JSON text and returns the value path selects in it.
+func GetJSON(data []byte, path string) (interface{}, error) {
+	doc, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return Get(doc, path)
+}
+
+// Decode decodes a JSON text into the generic form the queries work on:
+// map[string]interface{}, []interface{}, string, json.Number, bool and nil.
+// Numbers stay json.Number, so that integers beyond 2^53 such as IDs keep
+// every digit.
+func Decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("jsonpath: invalid json: %w", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("jsonpath: invalid json: trailing data")
+	}
+	return doc, nil
+}
+
+// TypeOf returns the JSON type name of a decoded value: "null", "boolean",
+// "number", "string", "array" or "object".
+func TypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, float32, int, int64, json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func (s step) apply(value interface{}) []interface{} {
+	switch s.kind {
+	case member:
+		if object, ok := value.(map[string]interface{}); ok {
+			if v, ok := object[s.name]; ok {
+				return []interface{}{v}
+			}
+		}
+	case index:
+		if array, ok := value.([]interface{}); ok {
+			i := s.index
+			if i < 0 {
+				i += len(array)
+			}
+			if i >= 0 && i < len(array) {
+				return []interface{}{array[i]}
+			}
+		}
+	case slice:
+		if array, ok := value.([]interface{}); ok {
+			start, end := bound(s.start, 0, len(array)), bound(s.end, len(array), len(array))
+			if start < end {
+				return append([]interface{}{}, array[start:end]...)
+			}
+		}
+	case wildcard:
+		return children(value)
+	}
+	return nil
+}
+
+func bound(b *int, fallback int, length int) int {
+	if b == nil {
+		return fallback
+	}
+	i := *b
+	if i < 0 {
+		i += length
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > length {
+		return length
+	}
+	return i
+}
+
+// children returns the members of an object in key order, or the elements
+// of an array.
+func children(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		return append([]interface{}{}, v...)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		values := make([]interface{}, 0, len(v))
+		for _, key := range keys {
+			values = append(values, v[key])
+		}
+		return values
+	}
+	return nil
+}
+
+// descendants returns value and everything nested in it, depth first.
+func descendants(value interface{}) []interface{} {
+	values := []interface{}{value}
+	for _, child := range children(value) {
+		values = append(values, descendants(child)...)
+	}
+	return values
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) parse() ([]step, error) {
+	if strings.HasPrefix(p.input, "$") {
+		p.pos++
+	}
+	steps := make([]step, 0)
+	for p.pos < len(p.input) {
+		recursive := false
+		switch p.input[p.pos] {
+		case '.':
+			p.pos++
+			if p.peek('.') {
+				p.pos++
+				recursive = true
+			}
+			if p.peek('[') {
+				if !recursive {
+					return nil, fmt.Errorf("unexpected '[' after '.' at %d", p.pos)
+				}
+				s, err := p.bracket()
+				if err != nil {
+					return nil, err
+				}
+				s.recursive = true
+				steps = append(steps, s)
+				continue
+			}
+			s, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			s.recursive = recursive
+			steps = append(steps, s)
+		case '[':
+			s, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, s)
+		default:
+			if len(steps) == 0 && p.pos == 0 {
+				// a bare leading member name, as in "data.items"
+				s, err := p.name()
+				if err != nil {
+					return nil, err
+				}
+				steps = append(steps, s)
+				continue
+			}
+			return nil, fmt.Errorf("unexpected %q at %d", p.input[p.pos], p.pos)
+		}
+	}
+	return steps, nil
+}
+
+func (p *parser) peek(c byte) bool {
+	return p.pos < len(p.input) && p.input[p.pos] == c
+}
+
+func (p *parser) name() (step, error) {
+	if p.peek('*') {
+		p.pos++
+		return step{kind: wildcard}, nil
+	}
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != '.' && p.input[p.pos] != '[' {
+		p.pos++
+	}
+	if start == p.pos {
+		return step{}, fmt.Errorf("missing member name at %d", start)
+	}
+	return step{kind: member, name: p.input[start:p.pos]}, nil
+}
+
+func (p *parser) bracket() (step, error) {
+	p.pos++ // '['
+	end := strings.IndexByte(p.input[p.pos:], ']')
+	if p.peek('\'') || p.peek('"') {
+		quote := p.input[p.pos]
+		closing := strings.IndexByte(p.input[p.pos+1:], quote)
+		if closing < 0 {
+			return step{}, fmt.Errorf("unterminated name at %d", p.pos)
+		}
+		name := p.input[p.pos+1 : p.pos+1+closing]
+		p.pos += closing + 2
+		if !p.peek(']') {
+			return step{}, fmt.Errorf("expected ']' at %d", p.pos)
+		}
+		p.pos++
+		return step{kind: member, name: name}, nil
+	}
+	if end < 0 {
+		return step{}, fmt.Errorf("unterminated '[' at %d", p.pos-1)
+	}
+	content := strings.TrimSpace(p.input[p.pos : p.pos+end])
+	p.pos += end + 1
+	if content == "*" {
+		return step{kind: wildcard}, nil
+	}
+	if colon := strings.IndexByte(content, ':'); colon >= 0 {
+		s := step{kind: slice}
+		var err error
+		if s.start, err = optionalInt(content[:colon]); err != nil {
+			return step{}, err
+		}
+		if s.end, err = optionalInt(content[colon+1:]); err != nil {
+			return step{}, err
+		}
+		return s, nil
+	}
+	i, err := strconv.Atoi(content)
+	if err != nil {
+		return step{}, fmt.Errorf("invalid index %q", content)
+	}
+	return step{kind: index, index: i}, nil
+}
+
+func optionalInt(s string) (*int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid slice bound %q", s)
+	}
+	return &i, nil
+}
@@ -0,0 +1,72 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const document = `{
+	"data": {
+		"items": [
+			{"id": 1, "name": "a", "tags": ["x"]},
+			{"id": 2, "name": "b", "tags": []},
+			{"id": 3, "name": "c", "price": 9.5}
+		],
+		"total": 3,
+		"first name": "jimmy"
+	}
+}`
+
+func TestGetJSON(t *testing.T) {
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"$.data.total", json.Number("3")},
+		{"data.total", json.Number("3")},
+		{"$.data.items[0].id", json.Number("1")},
+		{"$.data.items[-1].name", "c"},
+		{"$['data']['first name']", "jimmy"},
+		{`$.data["items"][1].tags`, []interface{}{}},
+		{"$.data.items[*].id", []interface{}{json.Number("1"), json.Number("2"), json.Number("3")}},
+		{"$.data.items[1:].name", []interface{}{"b", "c"}},
+		{"$..price", []interface{}{json.Number("9.5")}},
+		{"$.data.items[0].*", []interface{}{json.Number("1"), "a", []interface{}{"x"}}},
+	}
+	for _, test := range tests {
+		got, err := GetJSON([]byte(document), test.path)
+		if err != nil {
+			t.Errorf("%s: %v", test.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %#v, want %#v", test.path, got, test.want)
+		}
+	}
+}
+
+func TestGetJSONErrors(t *testing.T) {
+	if _, err := GetJSON([]byte(document), "$.data.items[5]"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing index: got %v, want ErrNotFound", err)
+	}
+	for _, path := range []string{"$.data.", "$.data[", "$.data['x", "$.data[a]", "$x"} {
+		if _, err := Compile(path); err == nil {
+			t.Errorf("%s: expected a compile error", path)
+		}
+	}
+	if _, err := GetJSON([]byte("{"), "$"); err == nil {
+		t.Error("invalid json: expected an error")
+	}
+	if _, err := GetJSON([]byte("{} {}"), "$"); err == nil {
+		t.Error("trailing data: expected an error")
+	}
+}
+
+func TestGetJSONLargeInteger(t *testing.T) {
+	got, err := GetJSON([]byte(`{"id": 9007199254740993}`), "$.id")
+	if err != nil || got != json.Number("9007199254740993") {
+		t.Errorf("got %#v %v, want every digit of the id", got, err)
+	}
+}
@@ -0,0 +1,300 @@
+// Package jsonschema validates JSON documents against JSON Schema draft 7
+// and draft 2020-12 schemas, in process.
+//
+// Every assertion keyword of both drafts is supported except
+// unevaluatedProperties and unevaluatedItems: a 2020-12 schema using them
+// fails to compile rather than accepting what they would reject. References
+// may point into the same document, to $id and $anchor targets, and to
+// schema files relative to the referring one. Remote references are not
+// fetched.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Violation is a single way in which a document does not satisfy a schema.
+type Violation struct {
+	// InstanceLocation is the JSON pointer of the offending value in the
+	// validated document, empty for the document itself.
+	InstanceLocation string
+	// KeywordLocation is the JSON pointer of the failed keyword in the
+	// schema.
+	KeywordLocation string
+	Message         string
+}
+
+func (v Violation) String() string {
+	location := v.InstanceLocation
+	if location == "" {
+		location = "(root)"
+	}
+	return location + ": " + v.Message
+}
+
+// Schema is a compiled schema together with every file it references.
+type Schema struct {
+	root   *document
+	loader *loader
+}
+
+type document struct {
+	path    string
+	root    interface{}
+	legacy  bool
+	anchors map[string]interface{}
+}
+
+type target struct {
+	doc    *document
+	schema interface{}
+}
+
+type loader struct {
+	docs map[string]*document
+	ids  map[string]target
+}
+
+// Compile compiles an inline schema. Relative file references are resolved
+// against the working directory.
+func Compile(data []byte) (*Schema, error) {
+	return compile(data, "")
+}
+
+// CompileFile compiles the schema stored in a file.
+func CompileFile(path string) (*Schema, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("jsonschema: %w", err)
+	}
+	return compile(data, abs)
+}
+
+func compile(data []byte, path string) (*Schema, error) {
+	l := &loader{docs: make(map[string]*document), ids: make(map[string]target)}
+	doc, err := l.add(data, path)
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{root: doc, loader: l}, nil
+}
+
+// Validate checks a document decoded by Decode and returns its violations,
+// none meaning it is valid.
+func (s *Schema) Validate(instance interface{}) []Violation {
+	v := &validator{loader: s.loader}
+	v.validate(s.root, s.root.root, instance, "", "")
+	return v.violations
+}
+
+// ValidateJSON decodes a JSON text and validates it.
+func (s *Schema) ValidateJSON(data []byte) ([]Violation, error) {
+	instance, err := Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return s.Validate(instance), nil
+}
+
+// Decode decodes a JSON text keeping numbers as json.Number, so that
+// integers and multipleOf are checked exactly.
+func Decode(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("jsonschema: invalid json: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("jsonschema: invalid json: trailing data")
+	}
+	return value, nil
+}
+
+// add decodes a schema document, indexes its identifiers and loads every
+// file it references.
+func (l *loader) add(data []byte, path string) (*document, error) {
+	root, err := Decode(data)
+	if err != nil {
+		if path != "" {
+			return nil, fmt.Errorf("%w in %s", err, path)
+		}
+		return nil, err
+	}
+	doc := &document{path: path, root: root, anchors: make(map[string]interface{})}
+	if object, ok := root.(map[string]interface{}); ok {
+		if dialect, ok := object["$schema"].(string); ok {
+			doc.legacy = strings.Contains(dialect, "draft-0")
+		}
+	}
+	if path != "" {
+		l.docs[path] = doc
+	}
+
+	refs := make([]string, 0)
+	unsupported := ""
+	walk(root, func(schema map[string]interface{}) {
+		if !doc.legacy {
+			for _, keyword := range []string{"unevaluatedProperties", "unevaluatedItems"} {
+				if _, ok := schema[keyword]; ok {
+					unsupported = keyword
+				}
+			}
+		}
+		if id, ok := schema["$id"].(string); ok {
+			if strings.HasPrefix(id, "#") {
+				doc.anchors[id[1:]] = schema
+			} else {
+				l.ids[strings.TrimSuffix(id, "#")] = target{doc: doc, schema: schema}
+			}
+		}
+		if anchor, ok := schema["$anchor"].(string); ok {
+			doc.anchors[anchor] = schema
+		}
+		if ref, ok := schema["$ref"].(string); ok {
+			refs = append(refs, ref)
+		}
+	})
+	if unsupported != "" {
+		if path != "" {
+			return nil, fmt.Errorf("jsonschema: keyword %s is not supported in %s", unsupported, path)
+		}
+		return nil, fmt.Errorf("jsonschema: keyword %s is not supported", unsupported)
+	}
+
+	for _, ref := range refs {
+		location := strings.SplitN(ref, "#", 2)[0]
+		if location == "" || isRemote(location) {
+			continue
+		}
+		if _, ok := l.ids[location]; ok {
+			continue
+		}
+		file := l.file(doc, location)
+		if _, ok := l.docs[file]; ok {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("jsonschema: cannot load $ref %q: %w", ref, err)
+		}
+		if _, err := l.add(data, file); err != nil {
+			return nil, err
+		}
+	}
+	return doc, nil
+}
+
+func (l *loader) file(doc *document, location string) string {
+	if filepath.IsAbs(location) {
+		return filepath.Clean(location)
+	}
+	if doc.path == "" {
+		abs, err := filepath.Abs(location)
+		if err != nil {
+			return location
+		}
+		return abs
+	}
+	return filepath.Join(filepath.Dir(doc.path), location)
+}
+
+// resolve returns the schema a $ref points to and the document it lives in.
+func (l *loader) resolve(doc *document, ref string) (*document, interface{}, error) {
+	parts := strings.SplitN(ref, "#", 2)
+	location, fragment := parts[0], ""
+	if len(parts) == 2 {
+		fragment = parts[1]
+	}
+
+	targetDoc, base := doc, doc.root
+	if location != "" {
+		if t, ok := l.ids[location]; ok {
+			targetDoc, base = t.doc, t.schema
+		} else if isRemote(location) {
+			return nil, nil, fmt.Errorf("remote $ref %q is not supported", ref)
+		} else if loaded, ok := l.docs[l.file(doc, location)]; ok {
+			targetDoc, base = loaded, loaded.root
+		} else {
+			return nil, nil, fmt.Errorf("cannot resolve $ref %q", ref)
+		}
+	}
+
+	if fragment == "" {
+		return targetDoc, base, nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		if schema, ok := targetDoc.anchors[fragment]; ok {
+			return targetDoc, schema, nil
+		}
+		return nil, nil, fmt.Errorf("cannot resolve anchor of $ref %q", ref)
+	}
+	schema := base
+	for _, token := range strings.Split(fragment[1:], "/") {
+		token, err := url.PathUnescape(token)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid $ref %q", ref)
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := schema.(type) {
+		case map[string]interface{}:
+			next, ok := node[token]
+			if !ok {
+				return nil, nil, fmt.Errorf("cannot resolve $ref %q", ref)
+			}
+			schema = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, nil, fmt.Errorf("cannot resolve $ref %q", ref)
+			}
+			schema = node[i]
+		default:
+			return nil, nil, fmt.Errorf("cannot resolve $ref %q", ref)
+		}
+	}
+	return targetDoc, schema, nil
+}
+
+func isRemote(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") || strings.HasPrefix(location, "urn:")
+}
+
+// walk calls fn for every schema object nested in value. Values of enum,
+// const and examples are data, not schemas, and are skipped.
+func walk(value interface{}, fn func(schema map[string]interface{})) {
+	switch node := value.(type) {
+	case map[string]interface{}:
+		fn(node)
+		for key, child := range node {
+			switch key {
+			case "enum", "const", "examples", "default":
+				continue
+			case "properties", "patternProperties", "$defs", "definitions", "dependentSchemas", "dependencies":
+				// maps from names to schemas
+				if children, ok := child.(map[string]interface{}); ok {
+					for _, schema := range children {
+						walk(schema, fn)
+					}
+					continue
+				}
+			}
+			walk(child, fn)
+		}
+	case []interface{}:
+		for _, child := range node {
+			walk(child, fn)
+		}
+	}
+}
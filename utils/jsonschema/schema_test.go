@@ -0,0 +1,106 @@
+package jsonschema
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestValidateFile(t *testing.T) {
+	schema, err := CompileFile("testdata/user.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := `{"id": 1, "name": "jimmy", "email": "j@example.com",
+		"address": {"city": "Shanghai", "code": "200000"}, "roles": ["owner", "reader"]}`
+	if violations, err := schema.ValidateJSON([]byte(valid)); err != nil || len(violations) != 0 {
+		t.Fatalf("valid document: %v %v", err, violations)
+	}
+
+	invalid := `{"id": 1.5, "name": "", "email": "nobody",
+		"address": {"code": "2000"}, "roles": ["reader", "reader"], "age": 3}`
+	violations, err := schema.ValidateJSON([]byte(invalid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		got = append(got, violation.InstanceLocation+" "+violation.KeywordLocation)
+	}
+	sort.Strings(got)
+	want := []string{
+		"/address/code /properties/address/$ref/properties/code/$ref/pattern",
+		"/address /properties/address/$ref/required",
+		"/age /additionalProperties",
+		"/email /properties/email/format",
+		"/id /properties/id/type",
+		"/name /properties/name/minLength",
+		"/roles /properties/roles/uniqueItems",
+		"/roles/0 /properties/roles/prefixItems/0/const",
+	}
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("violations\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestValidateKeywords(t *testing.T) {
+	tests := []struct {
+		schema   string
+		instance string
+		valid    bool
+	}{
+		{`{"type": ["string", "null"]}`, `null`, true},
+		{`{"multipleOf": 0.1}`, `0.3`, true},
+		{`{"exclusiveMaximum": 3}`, `3`, false},
+		{`{"items": [{"type": "string"}], "additionalItems": false}`, `["a", 1]`, false},
+		{`{"contains": {"const": 2}, "maxContains": 1}`, `[2, 2]`, false},
+		{`{"oneOf": [{"type": "integer"}, {"minimum": 0}]}`, `1`, false},
+		{`{"anyOf": [{"type": "string"}, {"minimum": 0}]}`, `-1`, false},
+		{`{"not": {"type": "string"}}`, `1`, true},
+		{`{"if": {"minimum": 10}, "then": {"multipleOf": 10}, "else": {"maximum": 5}}`, `7`, false},
+		{`{"dependentRequired": {"a": ["b"]}}`, `{"a": 1}`, false},
+		{`{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": false}`, `{"x-id": "1"}`, true},
+		{`{"propertyNames": {"maxLength": 2}}`, `{"abc": 1}`, false},
+		{`{"$defs": {"node": {"type": "object", "properties": {"next": {"$ref": "#/$defs/node"}}}}, "$ref": "#/$defs/node"}`,
+			`{"next": {"next": {"next": 1}}}`, false},
+		{`{"$defs": {"id": {"$anchor": "id", "type": "integer"}}, "$ref": "#id"}`, `"1"`, false},
+		{`{"enum": [1, "a", {"b": [true]}]}`, `{"b": [true]}`, true},
+		{`false`, `1`, false},
+	}
+	for _, test := range tests {
+		schema, err := Compile([]byte(test.schema))
+		if err != nil {
+			t.Errorf("%s: %v", test.schema, err)
+			continue
+		}
+		violations, err := schema.ValidateJSON([]byte(test.instance))
+		if err != nil {
+			t.Errorf("%s: %v", test.schema, err)
+			continue
+		}
+		if (len(violations) == 0) != test.valid {
+			t.Errorf("%s with %s: got %v, want valid %v", test.schema, test.instance, violations, test.valid)
+		}
+	}
+}
+
+func TestCompileUnsupported(t *testing.T) {
+	for _, schema := range []string{
+		`{"type": "object", "unevaluatedProperties": false}`,
+		`{"properties": {"tags": {"prefixItems": [{"type": "string"}], "unevaluatedItems": false}}}`,
+	} {
+		if _, err := Compile([]byte(schema)); err == nil {
+			t.Errorf("%s compiled", schema)
+		}
+	}
+	legacy := `{"$schema": "http://json-schema.org/draft-07/schema#", "unevaluatedProperties": false}`
+	if _, err := Compile([]byte(legacy)); err != nil {
+		t.Errorf("draft 7 schema: %v", err)
+	}
+	names := `{"properties": {"unevaluatedItems": {"type": "integer"}}}`
+	if _, err := Compile([]byte(names)); err != nil {
+		t.Errorf("property named like a keyword: %v", err)
+	}
+}
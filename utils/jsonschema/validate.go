@@ -0,0 +1,504 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxDepth stops $ref cycles that never advance into the instance.
+const maxDepth = 256
+
+type validator struct {
+	loader     *loader
+	violations []Violation
+	depth      int
+}
+
+func (v *validator) report(instanceLocation string, keywordLocation string, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{
+		InstanceLocation: instanceLocation,
+		KeywordLocation:  keywordLocation,
+		Message:          fmt.Sprintf(format, args...),
+	})
+}
+
+// valid reports whether instance satisfies schema, without recording.
+func (v *validator) valid(doc *document, schema interface{}, instance interface{}, iloc string, kloc string) bool {
+	probe := &validator{loader: v.loader, depth: v.depth}
+	probe.validate(doc, schema, instance, iloc, kloc)
+	return len(probe.violations) == 0
+}
+
+func (v *validator) validate(doc *document, schema interface{}, instance interface{}, iloc string, kloc string) {
+	switch s := schema.(type) {
+	case bool:
+		if !s {
+			v.report(iloc, kloc, "no value is allowed here")
+		}
+		return
+	case map[string]interface{}:
+		v.depth++
+		defer func() { v.depth-- }()
+		if v.depth > maxDepth {
+			v.report(iloc, kloc, "schema nesting too deep, is there a $ref cycle?")
+			return
+		}
+		if ref, ok := s["$ref"].(string); ok {
+			refDoc, refSchema, err := v.loader.resolve(doc, ref)
+			if err != nil {
+				v.report(iloc, kloc+"/$ref", "%s", err.Error())
+			} else {
+				v.validate(refDoc, refSchema, instance, iloc, kloc+"/$ref")
+			}
+			if doc.legacy {
+				// up to draft 7 keywords next to $ref are ignored
+				return
+			}
+		}
+		v.validateObject(doc, s, instance, iloc, kloc)
+	}
+}
+
+func (v *validator) validateObject(doc *document, s map[string]interface{}, instance interface{}, iloc string, kloc string) {
+	if types, ok := s["type"]; ok {
+		v.validateType(types, instance, iloc, kloc+"/type")
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, value := range enum {
+			if equal(value, instance) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.report(iloc, kloc+"/enum", "value %s is not one of %s", encode(instance), encode(enum))
+		}
+	}
+	if value, ok := s["const"]; ok && !equal(value, instance) {
+		v.report(iloc, kloc+"/const", "expected %s, but actual was %s", encode(value), encode(instance))
+	}
+
+	switch value := instance.(type) {
+	case json.Number:
+		v.validateNumber(s, value, iloc, kloc)
+	case string:
+		v.validateString(s, value, iloc, kloc)
+	case []interface{}:
+		v.validateArray(doc, s, value, iloc, kloc)
+	case map[string]interface{}:
+		v.validateProperties(doc, s, value, iloc, kloc)
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for i, sub := range all {
+			v.validate(doc, sub, instance, iloc, fmt.Sprintf("%s/allOf/%d", kloc, i))
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		matched := false
+		for i, sub := range anyOf {
+			if v.valid(doc, sub, instance, iloc, fmt.Sprintf("%s/anyOf/%d", kloc, i)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.report(iloc, kloc+"/anyOf", "value %s matches none of the anyOf schemas", encode(instance))
+		}
+	}
+	if one, ok := s["oneOf"].([]interface{}); ok {
+		matched := make([]string, 0)
+		for i, sub := range one {
+			if v.valid(doc, sub, instance, iloc, fmt.Sprintf("%s/oneOf/%d", kloc, i)) {
+				matched = append(matched, strconv.Itoa(i))
+			}
+		}
+		if len(matched) != 1 {
+			v.report(iloc, kloc+"/oneOf", "value %s must match exactly one oneOf schema, but matched %d [%s]",
+				encode(instance), len(matched), strings.Join(matched, ", "))
+		}
+	}
+	if not, ok := s["not"]; ok && v.valid(doc, not, instance, iloc, kloc+"/not") {
+		v.report(iloc, kloc+"/not", "value %s must not match the not schema", encode(instance))
+	}
+	if condition, ok := s["if"]; ok {
+		if v.valid(doc, condition, instance, iloc, kloc+"/if") {
+			if then, ok := s["then"]; ok {
+				v.validate(doc, then, instance, iloc, kloc+"/then")
+			}
+		} else if otherwise, ok := s["else"]; ok {
+			v.validate(doc, otherwise, instance, iloc, kloc+"/else")
+		}
+	}
+}
+
+func (v *validator) validateType(types interface{}, instance interface{}, iloc string, kloc string) {
+	names := make([]string, 0)
+	switch t := types.(type) {
+	case string:
+		names = append(names, t)
+	case []interface{}:
+		for _, name := range t {
+			if s, ok := name.(string); ok {
+				names = append(names, s)
+			}
+		}
+	}
+	for _, name := range names {
+		if isType(instance, name) {
+			return
+		}
+	}
+	v.report(iloc, kloc, "expected %s, but actual was %s", strings.Join(names, " or "), typeOf(instance))
+}
+
+func (v *validator) validateNumber(s map[string]interface{}, value json.Number, iloc string, kloc string) {
+	n, ok := rat(value)
+	if !ok {
+		return
+	}
+	if limit, ok := ratOf(s["minimum"]); ok && n.Cmp(limit) < 0 {
+		v.report(iloc, kloc+"/minimum", "value %s is less than minimum %s", value, s["minimum"])
+	}
+	if limit, ok := ratOf(s["maximum"]); ok && n.Cmp(limit) > 0 {
+		v.report(iloc, kloc+"/maximum", "value %s is greater than maximum %s", value, s["maximum"])
+	}
+	if limit, ok := ratOf(s["exclusiveMinimum"]); ok && n.Cmp(limit) <= 0 {
+		v.report(iloc, kloc+"/exclusiveMinimum", "value %s must be greater than %s", value, s["exclusiveMinimum"])
+	}
+	if limit, ok := ratOf(s["exclusiveMaximum"]); ok && n.Cmp(limit) >= 0 {
+		v.report(iloc, kloc+"/exclusiveMaximum", "value %s must be less than %s", value, s["exclusiveMaximum"])
+	}
+	if divisor, ok := ratOf(s["multipleOf"]); ok && divisor.Sign() > 0 {
+		if !new(big.Rat).Quo(n, divisor).IsInt() {
+			v.report(iloc, kloc+"/multipleOf", "value %s is not a multiple of %s", value, s["multipleOf"])
+		}
+	}
+}
+
+func (v *validator) validateString(s map[string]interface{}, value string, iloc string, kloc string) {
+	length := utf8.RuneCountInString(value)
+	if limit, ok := intOf(s["minLength"]); ok && length < limit {
+		v.report(iloc, kloc+"/minLength", "length %d is shorter than minLength %d", length, limit)
+	}
+	if limit, ok := intOf(s["maxLength"]); ok && length > limit {
+		v.report(iloc, kloc+"/maxLength", "length %d is longer than maxLength %d", length, limit)
+	}
+	if pattern, ok := s["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.report(iloc, kloc+"/pattern", "invalid pattern %q: %s", pattern, err.Error())
+		} else if !re.MatchString(value) {
+			v.report(iloc, kloc+"/pattern", "value %q does not match pattern %q", value, pattern)
+		}
+	}
+	if format, ok := s["format"].(string); ok {
+		if check, ok := formats[format]; ok && !check(value) {
+			v.report(iloc, kloc+"/format", "value %q is not a valid %s", value, format)
+		}
+	}
+}
+
+func (v *validator) validateArray(doc *document, s map[string]interface{}, value []interface{}, iloc string, kloc string) {
+	if limit, ok := intOf(s["minItems"]); ok && len(value) < limit {
+		v.report(iloc, kloc+"/minItems", "array has %d items, fewer than minItems %d", len(value), limit)
+	}
+	if limit, ok := intOf(s["maxItems"]); ok && len(value) > limit {
+		v.report(iloc, kloc+"/maxItems", "array has %d items, more than maxItems %d", len(value), limit)
+	}
+	if unique, ok := s["uniqueItems"].(bool); ok && unique {
+	duplicates:
+		for i := range value {
+			for j := i + 1; j < len(value); j++ {
+				if equal(value[i], value[j]) {
+					v.report(iloc, kloc+"/uniqueItems", "items %d and %d are equal", i, j)
+					break duplicates
+				}
+			}
+		}
+	}
+
+	// draft 2020-12 uses prefixItems and items, draft 7 items and additionalItems
+	rest, restKeyword := 0, ""
+	if prefix, ok := s["prefixItems"].([]interface{}); ok {
+		for i := 0; i < len(prefix) && i < len(value); i++ {
+			v.validate(doc, prefix[i], value[i], pointer(iloc, strconv.Itoa(i)), fmt.Sprintf("%s/prefixItems/%d", kloc, i))
+		}
+		rest, restKeyword = len(prefix), "items"
+	} else if tuple, ok := s["items"].([]interface{}); ok {
+		for i := 0; i < len(tuple) && i < len(value); i++ {
+			v.validate(doc, tuple[i], value[i], pointer(iloc, strconv.Itoa(i)), fmt.Sprintf("%s/items/%d", kloc, i))
+		}
+		rest, restKeyword = len(tuple), "additionalItems"
+	} else {
+		restKeyword = "items"
+	}
+	if items, ok := s[restKeyword]; ok {
+		for i := rest; i < len(value); i++ {
+			v.validate(doc, items, value[i], pointer(iloc, strconv.Itoa(i)), kloc+"/"+restKeyword)
+		}
+	}
+
+	if contains, ok := s["contains"]; ok {
+		count := 0
+		for i, item := range value {
+			if v.valid(doc, contains, item, pointer(iloc, strconv.Itoa(i)), kloc+"/contains") {
+				count++
+			}
+		}
+		minContains, ok := intOf(s["minContains"])
+		if !ok {
+			minContains = 1
+		}
+		if count < minContains {
+			v.report(iloc, kloc+"/contains", "array contains %d matching items, fewer than %d", count, minContains)
+		}
+		if maxContains, ok := intOf(s["maxContains"]); ok && count > maxContains {
+			v.report(iloc, kloc+"/maxContains", "array contains %d matching items, more than maxContains %d", count, maxContains)
+		}
+	}
+}
+
+func (v *validator) validateProperties(doc *document, s map[string]interface{}, value map[string]interface{}, iloc string, kloc string) {
+	if limit, ok := intOf(s["minProperties"]); ok && len(value) < limit {
+		v.report(iloc, kloc+"/minProperties", "object has %d properties, fewer than minProperties %d", len(value), limit)
+	}
+	if limit, ok := intOf(s["maxProperties"]); ok && len(value) > limit {
+		v.report(iloc, kloc+"/maxProperties", "object has %d properties, more than maxProperties %d", len(value), limit)
+	}
+	if required, ok := s["required"].([]interface{}); ok {
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, ok := value[name]; !ok {
+					v.report(iloc, kloc+"/required", "missing required property %q", name)
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	properties, _ := s["properties"].(map[string]interface{})
+	patterns, _ := s["patternProperties"].(map[string]interface{})
+	additional, hasAdditional := s["additionalProperties"]
+	for _, name := range names {
+		location := pointer(iloc, name)
+		matched := false
+		if sub, ok := properties[name]; ok {
+			matched = true
+			v.validate(doc, sub, value[name], location, kloc+"/properties/"+escape(name))
+		}
+		for pattern, sub := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil || !re.MatchString(name) {
+				continue
+			}
+			matched = true
+			v.validate(doc, sub, value[name], location, kloc+"/patternProperties/"+escape(pattern))
+		}
+		if !matched && hasAdditional {
+			if allowed, ok := additional.(bool); ok && !allowed {
+				v.report(location, kloc+"/additionalProperties", "property %q is not allowed", name)
+			} else {
+				v.validate(doc, additional, value[name], location, kloc+"/additionalProperties")
+			}
+		}
+		if propertyNames, ok := s["propertyNames"]; ok && !v.valid(doc, propertyNames, name, location, kloc+"/propertyNames") {
+			v.report(location, kloc+"/propertyNames", "property name %q is not valid", name)
+		}
+	}
+
+	dependencies := make(map[string]interface{})
+	for _, keyword := range []string{"dependencies", "dependentRequired", "dependentSchemas"} {
+		if deps, ok := s[keyword].(map[string]interface{}); ok {
+			for name, dep := range deps {
+				dependencies[name] = dep
+			}
+		}
+	}
+	for _, name := range names {
+		dep, ok := dependencies[name]
+		if !ok {
+			continue
+		}
+		if required, ok := dep.([]interface{}); ok {
+			for _, other := range required {
+				if other, ok := other.(string); ok {
+					if _, ok := value[other]; !ok {
+						v.report(iloc, kloc+"/dependentRequired/"+escape(name), "property %q requires property %q", name, other)
+					}
+				}
+			}
+		} else {
+			v.validate(doc, dep, value, iloc, kloc+"/dependentSchemas/"+escape(name))
+		}
+	}
+}
+
+func typeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func isType(value interface{}, name string) bool {
+	if name == "integer" {
+		number, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		n, ok := rat(number)
+		return ok && n.IsInt()
+	}
+	return typeOf(value) == name
+}
+
+func rat(number json.Number) (*big.Rat, bool) {
+	return new(big.Rat).SetString(string(number))
+}
+
+func ratOf(value interface{}) (*big.Rat, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return nil, false
+	}
+	return rat(number)
+}
+
+func intOf(value interface{}) (int, bool) {
+	n, ok := ratOf(value)
+	if !ok || !n.IsInt() {
+		return 0, false
+	}
+	return int(n.Num().Int64()), true
+}
+
+// equal compares decoded JSON values, numbers by their numeric value.
+func equal(x interface{}, y interface{}) bool {
+	switch xv := x.(type) {
+	case json.Number:
+		yv, ok := y.(json.Number)
+		if !ok {
+			return false
+		}
+		xn, xok := rat(xv)
+		yn, yok := rat(yv)
+		return xok && yok && xn.Cmp(yn) == 0
+	case []interface{}:
+		yv, ok := y.([]interface{})
+		if !ok || len(xv) != len(yv) {
+			return false
+		}
+		for i := range xv {
+			if !equal(xv[i], yv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		yv, ok := y.(map[string]interface{})
+		if !ok || len(xv) != len(yv) {
+			return false
+		}
+		for key, value := range xv {
+			other, ok := yv[key]
+			if !ok || !equal(value, other) {
+				return false
+			}
+		}
+		return true
+	}
+	return x == y
+}
+
+func encode(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	if len(data) > 80 {
+		return string(data[:77]) + "..."
+	}
+	return string(data)
+}
+
+func pointer(location string, token string) string {
+	return location + "/" + escape(token)
+}
+
+func escape(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+var (
+	emailPattern    = regexp.MustCompile(`^[^@\s]+@[^@\s]+$`)
+	hostnamePattern = regexp.MustCompile(`^(?i)[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*\.?$`)
+	uuidPattern     = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+)
+
+// formats holds the checked values of the format keyword, others are
+// accepted as annotations.
+var formats = map[string]func(string) bool{
+	"date-time": func(s string) bool {
+		_, err := time.Parse(time.RFC3339Nano, strings.ToUpper(s))
+		return err == nil
+	},
+	"date": func(s string) bool {
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	},
+	"time": func(s string) bool {
+		_, err := time.Parse("15:04:05.999999999Z07:00", strings.ToUpper(s))
+		return err == nil
+	},
+	"email": emailPattern.MatchString,
+	"hostname": func(s string) bool {
+		return len(s) <= 253 && hostnamePattern.MatchString(s)
+	},
+	"ipv4": func(s string) bool {
+		ip := net.ParseIP(s)
+		return ip != nil && ip.To4() != nil && !strings.Contains(s, ":")
+	},
+	"ipv6": func(s string) bool {
+		return net.ParseIP(s) != nil && strings.Contains(s, ":")
+	},
+	"uri": func(s string) bool {
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	},
+	"uri-reference": func(s string) bool {
+		_, err := url.Parse(s)
+		return err == nil
+	},
+	"uuid": uuidPattern.MatchString,
+	"regex": func(s string) bool {
+		_, err := regexp.Compile(s)
+		return err == nil
+	},
+}